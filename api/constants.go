@@ -41,6 +41,7 @@ const (
 	HTTPHeaderDeviceToken    = "Device-Token"
 	HTTPHeaderAcceptLanguage = "Accept-Language"
 	HTTPHeaderDeviceID       = "Device-ID"
+	HTTPHeaderRequestID      = "X-Request-ID"
 )
 
 // Key names used to store info in the HTTP handlers
@@ -53,6 +54,7 @@ const (
 	HandlerKeyPlatform     = "Platform"
 	HandlerKeyAppVersion   = "App-Version"
 	HandlerKeyDeviceID     = "Device-ID"
+	HandlerKeyRequestID    = "Request-ID"
 )
 
 // Parameters defined in the API urls
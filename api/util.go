@@ -175,13 +175,18 @@ func BuildForbiddenResponse() *Response {
 func GetLanguage(c *gin.Context) string {
 	var lang string
 	if l, exists := c.Get(HandlerKeyLanguage); exists {
-		lang = strings.ToLower(l.(string))
-	} else {
-		lang = DefaultLanguage
+		lang = l.(string)
 	}
 
+	return ResolveLanguage(lang)
+}
+
+// ResolveLanguage returns lang if it's a supported language, or the default one otherwise
+func ResolveLanguage(lang string) string {
+	lang = strings.ToLower(lang)
+
 	// if not a supported language, use the default
-	if !strings.Contains(SupportedLanguages, lang) {
+	if lang == "" || !strings.Contains(SupportedLanguages, lang) {
 		lang = DefaultLanguage
 	}
 
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+	"github.com/tuckyapps/lit-go-tools/api"
+	"github.com/tuckyapps/lit-go-tools/logger"
+)
+
+// Limits applied to the panic alerts sent to Slack
+const (
+	PanicAlertWindow    = 5 * time.Minute // identical panics are reported once per window
+	PanicAlertRateLimit = 10              // max number of alerts sent per window
+)
+
+// RecoverPanic is the handler used to recover from panics raised by the rest of
+// the pipeline. The panic is logged with its stack trace and reported to the
+// indicated Slack webhook; the client receives a 500 response.
+//
+// http.ErrAbortHandler is panicked again, so net/http aborts the response.
+//
+// It should be added before other handlers or routes:
+//
+//	r := gin.New()
+//	r.Use(handlers.RecoverPanic(webHook, settings))
+func RecoverPanic(webHook string, settings logger.LogSettings) gin.HandlerFunc {
+	notifier := newPanicNotifier(webHook, settings)
+
+	return func(c *gin.Context) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				// net/http aborts the response with it on purpose
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				notifier.notify(panicInfo{
+					route:     c.Request.Method + " " + ginRoute(c),
+					requestID: ginRequestID(c),
+					subject:   c.GetString(api.HandlerKeyTokenID),
+					value:     rec,
+					stack:     debug.Stack(),
+				})
+
+				if !c.Writer.Written() {
+					resp := api.BuildInternalErrorResponse()
+					resp.Language = api.GetLanguage(c)
+					resp.Send(c.Writer)
+				}
+
+				// prevent executing other handlers
+				c.Abort()
+			}
+		}()
+
+		c.Next()
+	}
+}
+
+// RecoverPanicEcho is the Echo version of RecoverPanic.
+//
+//	e := echo.New()
+//	e.Use(handlers.RecoverPanicEcho(webHook, settings))
+func RecoverPanicEcho(webHook string, settings logger.LogSettings) echo.MiddlewareFunc {
+	notifier := newPanicNotifier(webHook, settings)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					if rec == http.ErrAbortHandler {
+						panic(rec)
+					}
+
+					subject, _ := c.Get(api.HandlerKeyTokenID).(string)
+					notifier.notify(panicInfo{
+						route:     c.Request().Method + " " + c.Path(),
+						requestID: echoRequestID(c),
+						subject:   subject,
+						value:     rec,
+						stack:     debug.Stack(),
+					})
+
+					if !c.Response().Committed {
+						lang, _ := c.Get(api.HandlerKeyLanguage).(string)
+						if lang == "" {
+							lang = c.Request().Header.Get(api.HTTPHeaderAcceptLanguage)
+						}
+
+						resp := api.BuildInternalErrorResponse()
+						resp.Language = api.ResolveLanguage(lang)
+						resp.Send(c.Response())
+					}
+
+					err = nil
+				}
+			}()
+
+			return next(c)
+		}
+	}
+}
+
+// panicInfo holds the details of a recovered panic
+type panicInfo struct {
+	route     string
+	requestID string
+	subject   string
+	value     interface{}
+	stack     []byte
+}
+
+// panicNotifier logs recovered panics and reports them to Slack; identical panics
+// (same route and value) are reported once per PanicAlertWindow, and no more than
+// PanicAlertRateLimit alerts are sent per window.
+type panicNotifier struct {
+	webHook     string
+	settings    logger.LogSettings
+	mutex       sync.Mutex
+	windowStart time.Time
+	sent        int
+	reported    map[string]time.Time
+	now         func() time.Time // replaced in tests
+}
+
+func newPanicNotifier(webHook string, settings logger.LogSettings) *panicNotifier {
+	return &panicNotifier{
+		webHook:  webHook,
+		settings: settings,
+		reported: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (pn *panicNotifier) notify(info panicInfo) {
+	logger.GetLogger().Errorf("Panic recovered on '%s' (request: %s, subject: %s): %v\n%s",
+		info.route, info.requestID, info.subject, info.value, info.stack)
+
+	if pn.webHook == "" || pn.settings == nil {
+		return
+	}
+
+	if pn.allow(fmt.Sprintf("%s|%v", info.route, info.value)) {
+		title := fmt.Sprintf("Panic recovered on %s", info.route)
+		text := fmt.Sprintf("request: %s, subject: %s, panic: %v", info.requestID, info.subject, info.value)
+		logger.GetLogger().LogErrorToSlack(pn.webHook, title, text, pn.settings)
+	}
+}
+
+// allow returns true if an alert for key can be sent now
+func (pn *panicNotifier) allow(key string) bool {
+	pn.mutex.Lock()
+	defer pn.mutex.Unlock()
+
+	now := pn.now()
+
+	// start a new window, forgetting the panics reported in the previous one
+	if now.Sub(pn.windowStart) >= PanicAlertWindow {
+		pn.windowStart = now
+		pn.sent = 0
+		for k, reportedAt := range pn.reported {
+			if now.Sub(reportedAt) >= PanicAlertWindow {
+				delete(pn.reported, k)
+			}
+		}
+	}
+
+	if reportedAt, exists := pn.reported[key]; exists && now.Sub(reportedAt) < PanicAlertWindow {
+		return false
+	}
+
+	if pn.sent >= PanicAlertRateLimit {
+		return false
+	}
+
+	pn.reported[key] = now
+	pn.sent++
+	return true
+}
+
+// returns the route template, or the request path for unknown routes
+func ginRoute(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return c.Request.URL.Path
+}
+
+// returns the request ID, looking for it in the pipeline and in the HTTP headers
+func ginRequestID(c *gin.Context) string {
+	if id := c.GetString(api.HandlerKeyRequestID); id != "" {
+		return id
+	}
+	return requestIDFromHeaders(c.Request, c.Writer.Header())
+}
+
+func echoRequestID(c echo.Context) string {
+	if id, _ := c.Get(api.HandlerKeyRequestID).(string); id != "" {
+		return id
+	}
+	return requestIDFromHeaders(c.Request(), c.Response().Header())
+}
+
+func requestIDFromHeaders(req *http.Request, respHeader http.Header) string {
+	if id := req.Header.Get(api.HTTPHeaderRequestID); id != "" {
+		return id
+	}
+	return respHeader.Get(api.HTTPHeaderRequestID)
+}
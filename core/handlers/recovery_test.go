@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/logger"
+	"github.com/tuckyapps/lit-go-tools/logger/loggertest"
+)
+
+type slackSettings struct{}
+
+func (slackSettings) GetSlackEnabled() bool       { return true }
+func (slackSettings) GetHTTPClient() *http.Client { return http.DefaultClient }
+func (slackSettings) GetAppName() string          { return "test" }
+
+// serves a route that panics with the value of the 'value' query parameter, or
+// with http.ErrAbortHandler if it's 'abort'
+func panicValue(r *http.Request) interface{} {
+	value := r.URL.Query().Get("value")
+	if value == "abort" {
+		return http.ErrAbortHandler
+	}
+	return value
+}
+
+func testRecovery(t *testing.T, handler http.Handler) {
+	rec, restore := loggertest.Install()
+	defer restore()
+
+	serve := func(value string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic?value="+value, nil))
+		return w
+	}
+
+	// the client receives a 500, and identical panics are reported once
+	for i := 0; i < 3; i++ {
+		w := serve("boom")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	}
+	assert.Len(t, rec.EntriesAt(logger.LevelError), 3)
+	assert.Len(t, rec.SlackMessages(), 1)
+
+	// no more than PanicAlertRateLimit alerts per window
+	for i := 0; i < PanicAlertRateLimit+5; i++ {
+		serve(fmt.Sprint("panic", i))
+	}
+	assert.Len(t, rec.SlackMessages(), PanicAlertRateLimit)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { serve("abort") })
+}
+
+func TestRecoverPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RecoverPanic("https://hooks.slack.test", slackSettings{}))
+	r.GET("/panic", func(c *gin.Context) {
+		panic(panicValue(c.Request))
+	})
+
+	testRecovery(t, r)
+}
+
+func TestRecoverPanicEcho(t *testing.T) {
+	e := echo.New()
+	e.Use(RecoverPanicEcho("https://hooks.slack.test", slackSettings{}))
+	e.GET("/panic", func(c echo.Context) error {
+		panic(panicValue(c.Request()))
+	})
+
+	testRecovery(t, e)
+}
+
+func TestPanicNotifierWindow(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	pn := newPanicNotifier("https://hooks.slack.test", slackSettings{})
+	pn.now = func() time.Time { return now }
+
+	assert.True(t, pn.allow("a"))
+	assert.False(t, pn.allow("a"))
+	for i := 1; i < PanicAlertRateLimit; i++ {
+		assert.True(t, pn.allow(fmt.Sprint(i)))
+	}
+	assert.False(t, pn.allow("b"))
+
+	// a new window resets the rate limit and the reported panics
+	now = now.Add(PanicAlertWindow)
+	assert.True(t, pn.allow("a"))
+	assert.True(t, pn.allow("b"))
+}
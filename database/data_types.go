@@ -11,6 +11,7 @@ const (
 	DbTypeNumeric DBType = "NUMERIC"
 	DbTypeDate    DBType = "DATE"
 	DbTypeBool    DBType = "BOOLEAN"
+	DbTypeJSON    DBType = "JSON" // encoded with encoding/json
 	// others...
 )
 
@@ -2,6 +2,8 @@ package database
 
 import (
 	"bytes"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -10,9 +12,118 @@ import (
 	"github.com/tuckyapps/lit-go-tools/common"
 )
 
-// BuildUpdateSetQuery returns a string that can be used to build a set query, with
-// the values put as part of the string
-func BuildUpdateSetQuery(obj interface{}, fields []string) (string, error) {
+// QueryBuilder builds the parts of the statements with the identifier quoting
+// and the placeholders of a dialect. The package-level functions use MySQL.
+//
+//	b := database.NewQueryBuilder(access.Dialect())
+//	set, err := b.BuildParametrizedUpdateSetQuery(user, fields)
+type QueryBuilder struct {
+	dialect Dialect
+
+	// the parametrized and named builders don't quote the columns, as the
+	// package-level functions did before the dialects
+	plainColumns bool
+}
+
+// NewQueryBuilder creates a QueryBuilder of the dialect
+func NewQueryBuilder(d Dialect) *QueryBuilder {
+	return &QueryBuilder{dialect: d}
+}
+
+// Dialect returns the dialect of the builder
+func (b *QueryBuilder) Dialect() Dialect {
+	return b.dialect
+}
+
+// backs the package-level functions
+var mysqlBuilder = &QueryBuilder{dialect: MySQL, plainColumns: true}
+
+// returns the column as written by the parametrized and named builders
+func (b *QueryBuilder) setColumn(name string) string {
+	if b.plainColumns {
+		return name
+	}
+	return b.dialect.Quote(name)
+}
+
+// BuildUpdateSet is QueryBuilder.BuildUpdateSet with MySQL
+func BuildUpdateSet(obj interface{}, fields []string) (string, []interface{}, error) {
+	return mysqlBuilder.BuildUpdateSet(obj, fields)
+}
+
+// UnsafeBuildUpdateSetQuery is QueryBuilder.UnsafeBuildUpdateSetQuery with MySQL
+//
+// Deprecated: use BuildUpdateSet.
+func UnsafeBuildUpdateSetQuery(obj interface{}, fields []string) (string, error) {
+	return mysqlBuilder.UnsafeBuildUpdateSetQuery(obj, fields)
+}
+
+// BuildParametrizedUpdateSetQuery is QueryBuilder.BuildParametrizedUpdateSetQuery
+// with MySQL, without quoting the columns
+func BuildParametrizedUpdateSetQuery(obj interface{}, fields []string) (string, error) {
+	return mysqlBuilder.BuildParametrizedUpdateSetQuery(obj, fields)
+}
+
+// BuildNamedParametersUpdateSetQuery is
+// QueryBuilder.BuildNamedParametersUpdateSetQuery without quoting the columns,
+// so it can be used with any dialect
+func BuildNamedParametersUpdateSetQuery(obj interface{}, fields []string) (string, error) {
+	return mysqlBuilder.BuildNamedParametersUpdateSetQuery(obj, fields)
+}
+
+// BuildNamedParametersUpdateSetQueryV2 is
+// QueryBuilder.BuildNamedParametersUpdateSetQueryV2 without quoting the
+// columns, so it can be used with any dialect
+func BuildNamedParametersUpdateSetQueryV2(obj interface{}, fields []string) (string, []string, error) {
+	return mysqlBuilder.BuildNamedParametersUpdateSetQueryV2(obj, fields)
+}
+
+// GetAllFields is QueryBuilder.GetAllFields with MySQL
+func GetAllFields(obj interface{}, skipFields []string, quoted bool, asNamedParameter bool) (string, error) {
+	return mysqlBuilder.GetAllFields(obj, skipFields, quoted, asNamedParameter)
+}
+
+// BuildUpdateSet returns a set query of the fields with a parameter for each
+// value, and the values, in the same order. Values are converted like in
+// GetParameterValues. In PostgreSQL the parameters are $1, $2... so the next
+// one is len(args)+1.
+//
+//	set, args, err := b.BuildUpdateSet(user, []string{"Name", "Email"})
+//	...
+//	_, err = db.Exec("UPDATE users "+set+" WHERE id_user=?", append(args, user.ID)...)
+func (b *QueryBuilder) BuildUpdateSet(obj interface{}, fields []string) (string, []interface{}, error) {
+	objVal, objType, err := structOf(obj)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(fields) == 0 {
+		return "", nil, ErrInvalidFieldList
+	}
+
+	sets := make([]string, len(fields))
+	args := make([]interface{}, len(fields))
+	for i, name := range fields {
+		field, exists := objType.FieldByName(name)
+		if !exists {
+			return "", nil, fmt.Errorf("invalid field '%s'", name)
+		}
+
+		if args[i], err = parameterValue(field, objVal.FieldByName(name)); err != nil {
+			return "", nil, err
+		}
+		sets[i] = b.dialect.Quote(resolveColumnName(field)) + "=" + b.dialect.Placeholder(i+1)
+	}
+
+	return "SET " + strings.Join(sets, ","), args, nil
+}
+
+// UnsafeBuildUpdateSetQuery returns a string that can be used to build a set query, with
+// the values put as part of the string. The values are not escaped, so they
+// can break the query, or inject SQL; nil pointers panic.
+//
+// Deprecated: use BuildUpdateSet, which returns the values as parameters.
+func (b *QueryBuilder) UnsafeBuildUpdateSetQuery(obj interface{}, fields []string) (string, error) {
 
 	checkType := reflect.TypeOf(obj)
 
@@ -61,7 +172,7 @@ func BuildUpdateSetQuery(obj interface{}, fields []string) (string, error) {
 				}
 
 				// create field=value string
-				buf.WriteString(fmt.Sprintf(buildFieldSet(colType), colName, fieldValue))
+				buf.WriteString(fmt.Sprintf(buildFieldSet(colType), b.dialect.Quote(colName), fieldValue))
 			} else {
 				return "", fmt.Errorf("invalid field '%s'", fields[i])
 			}
@@ -79,8 +190,10 @@ func BuildUpdateSetQuery(obj interface{}, fields []string) (string, error) {
 }
 
 // BuildParametrizedUpdateSetQuery returns a string that can be used to build a set query,
-// using parameters instead of values (parameter used is '?')
-func BuildParametrizedUpdateSetQuery(obj interface{}, fields []string) (string, error) {
+// using parameters instead of values ('?', or '$1', '$2'... in PostgreSQL) and
+// the columns quoted by the dialect. The values returned by GetParameterValues
+// are in the same order.
+func (b *QueryBuilder) BuildParametrizedUpdateSetQuery(obj interface{}, fields []string) (string, error) {
 
 	checkType := reflect.TypeOf(obj)
 
@@ -110,7 +223,7 @@ func BuildParametrizedUpdateSetQuery(obj interface{}, fields []string) (string,
 
 			if field, exists = objType.FieldByName(fields[i]); exists {
 				colName := resolveColumnName(field)
-				buf.WriteString(fmt.Sprintf("%s=?", colName))
+				buf.WriteString(fmt.Sprintf("%s=%s", b.setColumn(colName), b.dialect.Placeholder(i+1)))
 			} else {
 				return "", fmt.Errorf("invalid field '%s'", fields[i])
 			}
@@ -128,8 +241,8 @@ func BuildParametrizedUpdateSetQuery(obj interface{}, fields []string) (string,
 }
 
 // BuildNamedParametersUpdateSetQuery returns a string that can be used to build a set query,
-// using named parameters (:param_name)
-func BuildNamedParametersUpdateSetQuery(obj interface{}, fields []string) (string, error) {
+// using named parameters (:param_name), which sqlx binds for every dialect
+func (b *QueryBuilder) BuildNamedParametersUpdateSetQuery(obj interface{}, fields []string) (string, error) {
 
 	checkType := reflect.TypeOf(obj)
 
@@ -159,7 +272,7 @@ func BuildNamedParametersUpdateSetQuery(obj interface{}, fields []string) (strin
 
 			if field, exists = objType.FieldByName(fields[i]); exists {
 				colName := resolveColumnName(field)
-				buf.WriteString(fmt.Sprintf("%s=:%s", colName, colName))
+				buf.WriteString(fmt.Sprintf("%s=:%s", b.setColumn(colName), colName))
 			} else {
 				return "", fmt.Errorf("invalid field '%s'", fields[i])
 			}
@@ -179,7 +292,7 @@ func BuildNamedParametersUpdateSetQuery(obj interface{}, fields []string) (strin
 // BuildNamedParametersUpdateSetQueryV2 returns a string that can be used to build a set query,
 // using named parameters (:param_name). This new version uses tag name, instead of struct's field
 // name to compare.
-func BuildNamedParametersUpdateSetQueryV2(obj interface{}, fields []string) (string, []string, error) {
+func (b *QueryBuilder) BuildNamedParametersUpdateSetQueryV2(obj interface{}, fields []string) (string, []string, error) {
 
 	checkType := reflect.TypeOf(obj)
 
@@ -211,7 +324,7 @@ func BuildNamedParametersUpdateSetQueryV2(obj interface{}, fields []string) (str
 			colName := resolveColumnName(fieldInstance)
 
 			if _, found := common.FindInStringArray(colName, fields); found {
-				buf.WriteString(fmt.Sprintf("%s=:%s", colName, colName))
+				buf.WriteString(fmt.Sprintf("%s=:%s", b.setColumn(colName), colName))
 				buf.WriteString(",")
 
 				fieldCount++
@@ -231,11 +344,12 @@ func BuildNamedParametersUpdateSetQueryV2(obj interface{}, fields []string) (str
 // Those fields indicated in 'skipFields' will not be included in the list; this is useful
 // when dealing with auto incremental fields.
 //
-// Flag 'quoted' makes all the fields to be wrapped as `field_name`; 'asNamedParameter' returns
+// Flag 'quoted' makes all the fields to be quoted by the dialect, e.g. `field_name` in
+// MySQL or "field_name" in PostgreSQL; 'asNamedParameter' returns
 // all fields as :field_name, useful for named queries. Flags are exclusive, use one or the other.
 //
 // Note: those fields without the 'db' attribute or marked with a dash (`db:"-"`) are ignored.
-func GetAllFields(obj interface{}, skipFields []string, quoted bool, asNamedParameter bool) (fieldList string, err error) {
+func (b *QueryBuilder) GetAllFields(obj interface{}, skipFields []string, quoted bool, asNamedParameter bool) (fieldList string, err error) {
 
 	checkType := reflect.TypeOf(obj)
 
@@ -265,7 +379,7 @@ func GetAllFields(obj interface{}, skipFields []string, quoted bool, asNamedPara
 
 			if colName != "-" && colName != "" {
 				if quoted {
-					buf.WriteString("`" + colName + "`" + ",")
+					buf.WriteString(b.dialect.Quote(colName) + ",")
 				} else if asNamedParameter {
 					buf.WriteString(":" + colName + ",")
 				} else {
@@ -282,7 +396,13 @@ func GetAllFields(obj interface{}, skipFields []string, quoted bool, asNamedPara
 	return
 }
 
-// GetParameterValues returns an array that may be used in a parametrized query
+// GetParameterValues returns an array that may be used in a parametrized query.
+//
+// Nil pointers are NULL, and the other pointers are dereferenced; values of
+// fields tagged with db_type:"json" are encoded as JSON, unless they're already
+// encoded: string, []byte, json.RawMessage or a driver.Valuer. Other values,
+// like time.Time, sql.NullString or any driver.Valuer, are converted by the
+// driver.
 func GetParameterValues(obj interface{}, fields []string, args ...interface{}) ([]interface{}, error) {
 
 	checkType := reflect.TypeOf(obj)
@@ -315,17 +435,12 @@ func GetParameterValues(obj interface{}, fields []string, args ...interface{}) (
 		params := make([]interface{}, len(fields)+otherFields)
 
 		for i := 0; i < len(fields); i++ {
-			if _, exists := objType.FieldByName(fields[i]); exists {
+			if field, exists := objType.FieldByName(fields[i]); exists {
 
 				// get field value
-				var fieldValue interface{}
-				fieldInstance := objVal.FieldByName(fields[i])
-				fieldKind := fieldInstance.Kind()
-
-				if fieldKind == reflect.Ptr {
-					fieldValue = fieldInstance.Elem().Interface()
-				} else {
-					fieldValue = fieldInstance.Interface()
+				fieldValue, err := parameterValue(field, objVal.FieldByName(fields[i]))
+				if err != nil {
+					return nil, err
 				}
 
 				// add field value to array
@@ -430,8 +545,8 @@ func GetChangedFields(original interface{}, new interface{}, skipFields []string
 
 func buildFieldSet(dbType DBType) string {
 	// format strings used to build sentences
-	quotedFormat := "`%s`='%v'"
-	unquotedFormat := "`%s`=%v"
+	quotedFormat := "%s='%v'"
+	unquotedFormat := "%s=%v"
 
 	switch dbType {
 	case DbTypeVarchar, DbTypeDate:
@@ -443,6 +558,55 @@ func buildFieldSet(dbType DBType) string {
 	}
 }
 
+// returns the value of obj, and its type, if it's a struct or a pointer to struct
+func structOf(obj interface{}) (reflect.Value, reflect.Type, error) {
+	checkType := reflect.TypeOf(obj)
+	if checkType == nil {
+		return reflect.Value{}, nil, errors.New("invalid obj type 'nil'")
+	}
+
+	objVal := reflect.ValueOf(obj)
+	if checkType.Kind() == reflect.Ptr {
+		if objVal.IsNil() {
+			return reflect.Value{}, nil, errors.New("invalid obj: nil pointer")
+		}
+		objVal = objVal.Elem()
+	}
+
+	if objVal.Kind() != reflect.Struct {
+		return reflect.Value{}, nil, fmt.Errorf("invalid obj type '%s'", checkType.Kind().String())
+	}
+	return objVal, objVal.Type(), nil
+}
+
+// returns the value of the field to use as a parameter: nil pointers are NULL,
+// and JSON columns are encoded unless the value is already encoded
+func parameterValue(field reflect.StructField, value reflect.Value) (interface{}, error) {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+
+	v := value.Interface()
+	if resolveColumnType(field) == DbTypeJSON {
+		switch raw := v.(type) {
+		case json.RawMessage:
+			return []byte(raw), nil
+		case string, []byte, driver.Valuer:
+			return v, nil
+		}
+
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON field '%s': %w", field.Name, err)
+		}
+		return string(encoded), nil
+	}
+	return v, nil
+}
+
 // resolves the column name associated to a struct's field;
 // tag 'db' is used for compatibility with "github.com/jmoiron/sqlx"
 func resolveColumnName(field reflect.StructField) (col string) {
@@ -1,9 +1,14 @@
 package database
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/datasource/sqlite3"
 )
 
 type User struct {
@@ -17,8 +22,8 @@ type User struct {
 	Active   bool    `db_type:"boolean"`
 }
 
-// test cases for BuildUpdateSetQuery()
-func TestBuildUpdateSetQuery(t *testing.T) {
+// test cases for UnsafeBuildUpdateSetQuery()
+func TestUnsafeBuildUpdateSetQuery(t *testing.T) {
 
 	witnesUserStr := "SET `password`='myhashedpassword',`address`='Luis Bonavita 1122',`id_user`=145,`city`='Montevideo',`country`='UY',`active`=true"
 
@@ -43,20 +48,20 @@ func TestBuildUpdateSetQuery(t *testing.T) {
 
 	// fields to update
 	dirtyFields := []string{"Password", "Address", "ID", "City", "Country", "Active"}
-	builtSetStr, err := BuildUpdateSetQuery(user, dirtyFields)
+	builtSetStr, err := UnsafeBuildUpdateSetQuery(user, dirtyFields)
 	if err == nil {
 		if builtSetStr != witnesUserStr {
-			t.Errorf("BuildUpdateSetQuery() returned a wrong string: %s", builtSetStr)
+			t.Errorf("UnsafeBuildUpdateSetQuery() returned a wrong string: %s", builtSetStr)
 		}
 	} else {
-		t.Errorf("BuildUpdateSetQuery() returned an error: %s", err.Error())
+		t.Errorf("UnsafeBuildUpdateSetQuery() returned an error: %s", err.Error())
 	}
 
 	// fields to update with a wrong field
 	dirtyFieldsInvalid := []string{"Password", "Address", "ID", "Status"}
-	_, err = BuildUpdateSetQuery(user, dirtyFieldsInvalid)
+	_, err = UnsafeBuildUpdateSetQuery(user, dirtyFieldsInvalid)
 	if err == nil {
-		t.Errorf("BuildUpdateSetQuery() should have returned an error")
+		t.Errorf("UnsafeBuildUpdateSetQuery() should have returned an error")
 	}
 }
 
@@ -335,3 +340,202 @@ func TestGetChangedFieldsWithPointers(t *testing.T) {
 	}
 
 }
+
+// test cases for the builders of each dialect
+func TestQueryBuilderDialects(t *testing.T) {
+	password := "myhashedpassword"
+	country := "UY"
+	user := &User{ID: 145, Password: &password, Country: &country, Active: true}
+	fields := []string{"Password", "ID", "Country", "Active"}
+
+	tests := []struct {
+		dialect      Dialect
+		set          string
+		parametrized string
+		quoted       string
+		named        string
+		namedV2      string
+	}{
+		{
+			MySQL,
+			"SET `password`='myhashedpassword',`id_user`=145,`country`='UY',`active`=true",
+			"SET `password`=?,`id_user`=?,`country`=?,`active`=?",
+			"`id_user`,`name`,`email`,`address`,`password`,`city`,`country`,`active`",
+			"SET `password`=:password,`id_user`=:id_user",
+			"SET `id_user`=:id_user,`password`=:password",
+		},
+		{
+			Postgres,
+			`SET "password"='myhashedpassword',"id_user"=145,"country"='UY',"active"=true`,
+			`SET "password"=$1,"id_user"=$2,"country"=$3,"active"=$4`,
+			`"id_user","name","email","address","password","city","country","active"`,
+			`SET "password"=:password,"id_user"=:id_user`,
+			`SET "id_user"=:id_user,"password"=:password`,
+		},
+		{
+			SQLite3,
+			`SET "password"='myhashedpassword',"id_user"=145,"country"='UY',"active"=true`,
+			`SET "password"=?,"id_user"=?,"country"=?,"active"=?`,
+			`"id_user","name","email","address","password","city","country","active"`,
+			`SET "password"=:password,"id_user"=:id_user`,
+			`SET "id_user"=:id_user,"password"=:password`,
+		},
+	}
+
+	for _, test := range tests {
+		b := NewQueryBuilder(test.dialect)
+		assert.Equal(t, test.dialect, b.Dialect())
+
+		set, err := b.UnsafeBuildUpdateSetQuery(user, fields)
+		assert.NoError(t, err)
+		assert.Equal(t, test.set, set, test.dialect.Name())
+
+		parametrized, err := b.BuildParametrizedUpdateSetQuery(user, fields)
+		assert.NoError(t, err)
+		assert.Equal(t, test.parametrized, parametrized, test.dialect.Name())
+
+		quoted, err := b.GetAllFields(user, nil, true, false)
+		assert.NoError(t, err)
+		assert.Equal(t, test.quoted, quoted, test.dialect.Name())
+
+		// named parameters are the same in every dialect, sqlx binds them
+		named, err := b.BuildNamedParametersUpdateSetQuery(user, []string{"Password", "ID"})
+		assert.NoError(t, err)
+		assert.Equal(t, test.named, named, test.dialect.Name())
+
+		namedV2, list, err := b.BuildNamedParametersUpdateSetQueryV2(user, []string{"password", "id_user"})
+		assert.NoError(t, err)
+		assert.Equal(t, test.namedV2, namedV2, test.dialect.Name())
+		assert.Equal(t, []string{"id_user", "password"}, list, test.dialect.Name())
+	}
+}
+
+type Profile struct {
+	ID        int               `db:"id"`
+	Name      *string           `db:"name"`
+	Bio       string            `db:"bio"`
+	Birthday  time.Time         `db:"birthday"`
+	Nickname  sql.NullString    `db:"nickname"`
+	Status    status            `db:"status"`
+	Settings  map[string]string `db:"settings" db_type:"json"`
+	Tags      *[]string         `db:"tags" db_type:"json"`
+	Anonymous bool              `db:"anonymous"`
+}
+
+// status implements driver.Valuer, storing the name
+type status int
+
+func (s status) Value() (driver.Value, error) {
+	return []string{"inactive", "active"}[s], nil
+}
+
+// test cases for BuildUpdateSet()
+func TestBuildUpdateSet(t *testing.T) {
+	birthday := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	profile := &Profile{
+		ID:       1,
+		Bio:      "it's'; DROP TABLE profiles; --",
+		Birthday: birthday,
+		Nickname: sql.NullString{String: "pepe", Valid: true},
+		Status:   1,
+		Settings: map[string]string{"theme": "dark"},
+	}
+	fields := []string{"Name", "Bio", "Birthday", "Nickname", "Status", "Settings", "Tags"}
+
+	set, args, err := BuildUpdateSet(profile, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, "SET `name`=?,`bio`=?,`birthday`=?,`nickname`=?,`status`=?,`settings`=?,`tags`=?", set)
+	assert.Equal(t, []interface{}{
+		nil,
+		"it's'; DROP TABLE profiles; --",
+		birthday,
+		sql.NullString{String: "pepe", Valid: true},
+		status(1),
+		`{"theme":"dark"}`,
+		nil,
+	}, args)
+
+	set, _, err = NewQueryBuilder(Postgres).BuildUpdateSet(profile, []string{"Bio", "Anonymous"})
+	assert.NoError(t, err)
+	assert.Equal(t, `SET "bio"=$1,"anonymous"=$2`, set)
+
+	_, _, err = BuildUpdateSet(profile, []string{"Missing"})
+	assert.Error(t, err)
+	_, _, err = BuildUpdateSet(profile, nil)
+	assert.Equal(t, ErrInvalidFieldList, err)
+	_, _, err = BuildUpdateSet((*Profile)(nil), fields)
+	assert.Error(t, err)
+	_, _, err = BuildUpdateSet("profile", fields)
+	assert.Error(t, err)
+
+	// the values are sent as parameters, so they don't change the statement
+	db, err := sqlite3.Init(":memory:")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE profiles (id INTEGER PRIMARY KEY, name TEXT, bio TEXT, birthday TIMESTAMP,
+		nickname TEXT, status TEXT, settings TEXT, tags TEXT, anonymous BOOLEAN)`)
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO profiles (id, name) VALUES (1, 'before')")
+	assert.NoError(t, err)
+
+	set, args, err = NewQueryBuilder(SQLite3).BuildUpdateSet(profile, fields)
+	assert.NoError(t, err)
+	_, err = db.Exec("UPDATE profiles "+set+" WHERE id=?", append(args, profile.ID)...)
+	assert.NoError(t, err)
+
+	var stored struct {
+		Name     *string `db:"name"`
+		Bio      string  `db:"bio"`
+		Status   string  `db:"status"`
+		Settings string  `db:"settings"`
+		Tags     *string `db:"tags"`
+	}
+	assert.NoError(t, db.Get(&stored, "SELECT name, bio, status, settings, tags FROM profiles WHERE id=1"))
+	assert.Nil(t, stored.Name)
+	assert.Equal(t, profile.Bio, stored.Bio)
+	assert.Equal(t, "active", stored.Status)
+	assert.Equal(t, `{"theme":"dark"}`, stored.Settings)
+	assert.Nil(t, stored.Tags)
+}
+
+// nil pointers are NULL in GetParameterValues too
+func TestGetParameterValuesNil(t *testing.T) {
+	params, err := GetParameterValues(&User{ID: 1}, []string{"ID", "Name"})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1, nil}, params)
+}
+
+type Event struct {
+	ID      int             `db:"id"`
+	Payload string          `db:"payload" db_type:"json"`
+	Raw     []byte          `db:"raw" db_type:"json"`
+	Message json.RawMessage `db:"message" db_type:"json"`
+	Data    *string         `db:"data" db_type:"json"`
+	Labels  []string        `db:"labels" db_type:"json"`
+}
+
+// JSON fields already encoded are not encoded again
+func TestGetParameterValuesJSON(t *testing.T) {
+	data := `{"b":2}`
+	event := &Event{
+		ID:      1,
+		Payload: `{"a":1}`,
+		Raw:     []byte(`[1,2]`),
+		Message: json.RawMessage(`"hi"`),
+		Data:    &data,
+		Labels:  []string{"x"},
+	}
+
+	params, err := GetParameterValues(event, []string{"Payload", "Raw", "Message", "Data", "Labels"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []interface{}{`{"a":1}`, []byte(`[1,2]`), []byte(`"hi"`), `{"b":2}`, `["x"]`}, params)
+	}
+
+	_, args, err := BuildUpdateSet(event, []string{"Payload", "Message"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []interface{}{`{"a":1}`, []byte(`"hi"`)}, args)
+	}
+}
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrDialectNotSupported is returned when there's no dialect for a driver
+var ErrDialectNotSupported = errors.New("dialect not supported")
+
+// LockMode is a row locking clause of a SELECT
+type LockMode int
+
+// Lock modes
+const (
+	LockNone      LockMode = iota
+	LockForUpdate          // exclusive lock, e.g. to update the rows
+	LockForShare           // shared lock, to prevent changes while reading
+)
+
+// Dialect holds the SQL differences between the database drivers
+type Dialect interface {
+	// Name returns the name of the driver: mysql, postgres or sqlite3
+	Name() string
+
+	// Placeholder returns the n-th parameter of a statement, starting at 1
+	Placeholder(n int) string
+
+	// Quote returns the identifier quoted, e.g. a column name that is a
+	// reserved word
+	Quote(identifier string) string
+
+	// RandomFunc returns the function that returns a random number
+	RandomFunc() string
+
+	// LimitOffset returns the clause that limits the rows returned; a limit
+	// lower than 1 returns all of them
+	LimitOffset(limit, offset int) string
+
+	// Upsert returns an INSERT of columns into table that updates the columns
+	// in update when a row with the same conflict columns (a primary key or a
+	// unique index) exists. If update is empty, the existing row is kept.
+	Upsert(table string, columns, conflict, update []string) string
+
+	// SupportsReturning returns true if INSERT, UPDATE and DELETE statements
+	// can return the rows changed
+	SupportsReturning() bool
+
+	// Returning returns the RETURNING clause of the columns, or an empty
+	// string if it's not supported
+	Returning(columns ...string) string
+
+	// LockClause returns the clause appended to a SELECT to lock the rows, or
+	// an empty string if the database doesn't lock rows
+	LockClause(mode LockMode) string
+
+	// SupportsAdvisoryLocks returns true if the database has named locks,
+	// used by datasource.Locker
+	SupportsAdvisoryLocks() bool
+}
+
+// Dialects of the drivers supported by datasource
+var (
+	MySQL    Dialect = mysqlDialect{}
+	Postgres Dialect = postgresDialect{}
+	SQLite3  Dialect = sqliteDialect{}
+)
+
+// GetDialect returns the dialect of the driver, or ErrDialectNotSupported
+func GetDialect(driverName string) (Dialect, error) {
+	switch driverName {
+	case "mysql":
+		return MySQL, nil
+	case "postgres":
+		return Postgres, nil
+	case "sqlite3":
+		return SQLite3, nil
+	default:
+		return nil, ErrDialectNotSupported
+	}
+}
+
+// quotes identifier with q, doubling q if the identifier has it; qualified
+// names (table.column) are quoted by part
+func quoteWith(identifier string, q string) string {
+	parts := strings.Split(identifier, ".")
+	for i, p := range parts {
+		parts[i] = q + strings.Replace(p, q, q+q, -1) + q
+	}
+	return strings.Join(parts, ".")
+}
+
+// returns the list of columns quoted by d, separated by commas
+func quoteAll(d Dialect, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = d.Quote(c)
+	}
+	return strings.Join(quoted, ",")
+}
+
+// returns the INSERT statement of the columns, without the conflict clause
+func insertStatement(d Dialect, table string, columns []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = d.Placeholder(i + 1)
+	}
+	return "INSERT INTO " + d.Quote(table) + " (" + quoteAll(d, columns) + ") VALUES (" +
+		strings.Join(placeholders, ",") + ")"
+}
+
+// returns the ON CONFLICT clause of PostgreSQL and SQLite
+func onConflict(d Dialect, conflict, update []string) string {
+	clause := " ON CONFLICT (" + quoteAll(d, conflict) + ")"
+	if len(update) == 0 {
+		return clause + " DO NOTHING"
+	}
+
+	sets := make([]string, len(update))
+	for i, c := range update {
+		sets[i] = d.Quote(c) + "=excluded." + d.Quote(c)
+	}
+	return clause + " DO UPDATE SET " + strings.Join(sets, ",")
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string                   { return "mysql" }
+func (mysqlDialect) Placeholder(n int) string       { return "?" }
+func (mysqlDialect) Quote(identifier string) string { return quoteWith(identifier, "`") }
+func (mysqlDialect) RandomFunc() string             { return "rand()" }
+func (mysqlDialect) SupportsReturning() bool        { return false }
+func (mysqlDialect) Returning(...string) string     { return "" }
+func (mysqlDialect) SupportsAdvisoryLocks() bool    { return true }
+
+func (mysqlDialect) LimitOffset(limit, offset int) string {
+	switch {
+	case limit > 0 && offset > 0:
+		return "LIMIT " + strconv.Itoa(limit) + " OFFSET " + strconv.Itoa(offset)
+	case limit > 0:
+		return "LIMIT " + strconv.Itoa(limit)
+	case offset > 0:
+		// MySQL doesn't allow OFFSET without LIMIT
+		return "LIMIT 18446744073709551615 OFFSET " + strconv.Itoa(offset)
+	default:
+		return ""
+	}
+}
+
+func (d mysqlDialect) Upsert(table string, columns, conflict, update []string) string {
+	if len(update) == 0 {
+		return "INSERT IGNORE" + strings.TrimPrefix(insertStatement(d, table, columns), "INSERT")
+	}
+
+	// MySQL uses the primary key and the unique indexes, conflict isn't needed
+	sets := make([]string, len(update))
+	for i, c := range update {
+		sets[i] = d.Quote(c) + "=VALUES(" + d.Quote(c) + ")"
+	}
+	return insertStatement(d, table, columns) + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ",")
+}
+
+func (mysqlDialect) LockClause(mode LockMode) string {
+	switch mode {
+	case LockForUpdate:
+		return "FOR UPDATE"
+	case LockForShare:
+		return "LOCK IN SHARE MODE"
+	default:
+		return ""
+	}
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string                   { return "postgres" }
+func (postgresDialect) Placeholder(n int) string       { return "$" + strconv.Itoa(n) }
+func (postgresDialect) Quote(identifier string) string { return quoteWith(identifier, `"`) }
+func (postgresDialect) RandomFunc() string             { return "random()" }
+func (postgresDialect) SupportsReturning() bool        { return true }
+func (postgresDialect) SupportsAdvisoryLocks() bool    { return true }
+
+func (d postgresDialect) Returning(columns ...string) string {
+	if len(columns) == 0 {
+		return ""
+	}
+	return "RETURNING " + quoteAll(d, columns)
+}
+
+func (postgresDialect) LimitOffset(limit, offset int) string {
+	var clauses []string
+	if limit > 0 {
+		clauses = append(clauses, "LIMIT "+strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		clauses = append(clauses, "OFFSET "+strconv.Itoa(offset))
+	}
+	return strings.Join(clauses, " ")
+}
+
+func (d postgresDialect) Upsert(table string, columns, conflict, update []string) string {
+	return insertStatement(d, table, columns) + onConflict(d, conflict, update)
+}
+
+func (postgresDialect) LockClause(mode LockMode) string {
+	switch mode {
+	case LockForUpdate:
+		return "FOR UPDATE"
+	case LockForShare:
+		return "FOR SHARE"
+	default:
+		return ""
+	}
+}
+
+// sqliteDialect targets the SQLite version bundled with the driver, which
+// doesn't support RETURNING
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string                   { return "sqlite3" }
+func (sqliteDialect) Placeholder(n int) string       { return "?" }
+func (sqliteDialect) Quote(identifier string) string { return quoteWith(identifier, `"`) }
+func (sqliteDialect) RandomFunc() string             { return "random()" }
+func (sqliteDialect) SupportsReturning() bool        { return false }
+func (sqliteDialect) Returning(...string) string     { return "" }
+func (sqliteDialect) SupportsAdvisoryLocks() bool    { return false }
+
+// SQLite locks the whole database in writes, there are no row locks
+func (sqliteDialect) LockClause(LockMode) string { return "" }
+
+func (sqliteDialect) LimitOffset(limit, offset int) string {
+	switch {
+	case limit > 0 && offset > 0:
+		return "LIMIT " + strconv.Itoa(limit) + " OFFSET " + strconv.Itoa(offset)
+	case limit > 0:
+		return "LIMIT " + strconv.Itoa(limit)
+	case offset > 0:
+		return "LIMIT -1 OFFSET " + strconv.Itoa(offset)
+	default:
+		return ""
+	}
+}
+
+func (d sqliteDialect) Upsert(table string, columns, conflict, update []string) string {
+	return insertStatement(d, table, columns) + onConflict(d, conflict, update)
+}
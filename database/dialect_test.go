@@ -0,0 +1,78 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDialect(t *testing.T) {
+	for _, name := range []string{"mysql", "postgres", "sqlite3"} {
+		d, err := GetDialect(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, d.Name())
+	}
+
+	_, err := GetDialect("oracle")
+	assert.Equal(t, ErrDialectNotSupported, err)
+}
+
+func TestDialects(t *testing.T) {
+	tests := []struct {
+		dialect     Dialect
+		placeholder string
+		quoted      string
+		random      string
+		returning   string
+		forUpdate   string
+		forShare    string
+		locks       bool
+	}{
+		{MySQL, "?", "`users`.`order`", "rand()", "", "FOR UPDATE", "LOCK IN SHARE MODE", true},
+		{Postgres, "$3", `"users"."order"`, "random()", `RETURNING "id","created_at"`, "FOR UPDATE", "FOR SHARE", true},
+		{SQLite3, "?", `"users"."order"`, "random()", "", "", "", false},
+	}
+
+	for _, test := range tests {
+		d := test.dialect
+		assert.Equal(t, test.placeholder, d.Placeholder(3), d.Name())
+		assert.Equal(t, test.quoted, d.Quote("users.order"), d.Name())
+		assert.Equal(t, test.random, d.RandomFunc(), d.Name())
+		assert.Equal(t, test.returning != "", d.SupportsReturning(), d.Name())
+		assert.Equal(t, test.returning, d.Returning("id", "created_at"), d.Name())
+		assert.Equal(t, test.forUpdate, d.LockClause(LockForUpdate), d.Name())
+		assert.Equal(t, test.forShare, d.LockClause(LockForShare), d.Name())
+		assert.Equal(t, "", d.LockClause(LockNone), d.Name())
+		assert.Equal(t, test.locks, d.SupportsAdvisoryLocks(), d.Name())
+		assert.Equal(t, "LIMIT 10 OFFSET 20", d.LimitOffset(10, 20), d.Name())
+		assert.Equal(t, "LIMIT 10", d.LimitOffset(10, 0), d.Name())
+		assert.Equal(t, "", d.LimitOffset(0, 0), d.Name())
+	}
+
+	assert.Equal(t, "LIMIT 18446744073709551615 OFFSET 5", MySQL.LimitOffset(0, 5))
+	assert.Equal(t, "OFFSET 5", Postgres.LimitOffset(0, 5))
+	assert.Equal(t, "LIMIT -1 OFFSET 5", SQLite3.LimitOffset(0, 5))
+}
+
+func TestQuoteEscapes(t *testing.T) {
+	assert.Equal(t, "`we``ird`", MySQL.Quote("we`ird"))
+	assert.Equal(t, `"we""ird"`, Postgres.Quote(`we"ird`))
+}
+
+func TestUpsert(t *testing.T) {
+	columns := []string{"id", "name", "email"}
+
+	assert.Equal(t,
+		"INSERT INTO `users` (`id`,`name`,`email`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`email`=VALUES(`email`)",
+		MySQL.Upsert("users", columns, []string{"id"}, []string{"name", "email"}))
+	assert.Equal(t,
+		"INSERT IGNORE INTO `users` (`id`,`name`,`email`) VALUES (?,?,?)",
+		MySQL.Upsert("users", columns, []string{"id"}, nil))
+
+	assert.Equal(t,
+		`INSERT INTO "users" ("id","name","email") VALUES ($1,$2,$3) ON CONFLICT ("id") DO UPDATE SET "name"=excluded."name"`,
+		Postgres.Upsert("users", columns, []string{"id"}, []string{"name"}))
+	assert.Equal(t,
+		`INSERT INTO "users" ("id","name","email") VALUES (?,?,?) ON CONFLICT ("id") DO NOTHING`,
+		SQLite3.Upsert("users", columns, []string{"id"}, nil))
+}
@@ -0,0 +1,267 @@
+// Package cache stores typed values in an InMemoryDB. Values are encoded with a
+// pluggable Codec, concurrent loads of the same key are deduplicated, missing
+// values can be cached too, and keys can be invalidated by tag.
+//
+//	c := cache.New(db, cache.Options{NegativeTTL: time.Minute})
+//
+//	var user User
+//	err := c.GetOrLoad(ctx, "user:42", time.Hour, &user, func(ctx context.Context) (interface{}, error) {
+//		return repository.FindUser(ctx, 42) // returns cache.ErrNotFound if there's no user
+//	}, "users")
+//
+//	// later, when users change
+//	c.InvalidateTags(ctx, "users")
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/tuckyapps/lit-go-tools/datasource"
+	"github.com/tuckyapps/lit-go-tools/logger"
+)
+
+// Errors returned by the cache
+var (
+	// ErrNotFound is returned by loaders when the value doesn't exist, so it's
+	// cached for NegativeTTL. It's returned by Get and GetOrLoad while cached.
+	ErrNotFound = errors.New("cache: value not found")
+
+	// ErrCacheMiss is returned by Get when the key is not cached
+	ErrCacheMiss = errors.New("cache: miss")
+)
+
+// first byte of the stored values
+const (
+	markerNotFound byte = iota
+	markerValue
+)
+
+// default prefix of the keys
+const defaultPrefix = "cache:"
+
+// Loader returns the value to cache, or ErrNotFound if it doesn't exist
+type Loader func(ctx context.Context) (interface{}, error)
+
+// Options holds the configuration of a Cache
+type Options struct {
+	// Codec encodes the values; JSON by default
+	Codec Codec
+
+	// Prefix is prepended to the keys, "cache:" by default
+	Prefix string
+
+	// Jitter is the max fraction of the TTL added randomly to each key, so keys
+	// stored together don't expire at the same time (e.g. 0.1 adds up to 10%)
+	Jitter float64
+
+	// NegativeTTL is the time ErrNotFound is cached; zero disables it
+	NegativeTTL time.Duration
+}
+
+// Cache stores typed values in an InMemoryDB. It's safe for concurrent use.
+type Cache struct {
+	db      datasource.InMemoryDB
+	options Options
+	group   flightGroup
+
+	randMutex sync.Mutex
+	rand      *rand.Rand
+}
+
+// New creates a cache that stores the values in db
+func New(db datasource.InMemoryDB, options Options) *Cache {
+	if options.Codec == nil {
+		options.Codec = JSON
+	}
+	if options.Prefix == "" {
+		options.Prefix = defaultPrefix
+	}
+
+	return &Cache{
+		db:      db,
+		options: options,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Get decodes the cached value of key into dest, which must be a pointer. It
+// returns ErrCacheMiss if the key is not cached, or ErrNotFound if it was
+// cached as not found.
+func (c *Cache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, err := c.db.Get(ctx, c.options.Prefix+key)
+	if errors.Is(err, datasource.ErrKeyNotFound) {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+	return c.decode(data, dest)
+}
+
+// Set caches value for ttl (zero means no expiration), associated with tags.
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error {
+	data, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	return c.store(ctx, key, data, ttl, tags)
+}
+
+// GetOrLoad decodes the cached value of key into dest. If it's not cached,
+// loader is called and its result is cached for ttl, associated with tags.
+// Concurrent calls for the same key share a single load, run with the context
+// of the first caller. Errors reading or writing the cache are logged, and the
+// loader is used as if the key wasn't cached.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, dest interface{}, loader Loader, tags ...string) error {
+	err := c.Get(ctx, key, dest)
+	if err == nil || err == ErrNotFound {
+		return err
+	}
+	if err != ErrCacheMiss {
+		logger.GetLogger().Errorf("Error reading the key %s from cache: %s", key, err)
+	}
+
+	data, err, _ := c.group.do(key, func() ([]byte, error) {
+		return c.load(ctx, key, ttl, loader, tags)
+	})
+	if err != nil {
+		return err
+	}
+	return c.decode(data, dest)
+}
+
+// runs the loader and caches its result, returning it encoded
+func (c *Cache) load(ctx context.Context, key string, ttl time.Duration, loader Loader, tags []string) ([]byte, error) {
+	value, err := loader(ctx)
+	if errors.Is(err, ErrNotFound) {
+		if c.options.NegativeTTL > 0 {
+			c.storeLogged(ctx, key, []byte{markerNotFound}, c.options.NegativeTTL, tags)
+		}
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	c.storeLogged(ctx, key, data, ttl, tags)
+	return data, nil
+}
+
+// Delete removes the keys from the cache
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.options.Prefix + key
+	}
+
+	_, err := c.db.Delete(ctx, prefixed...)
+	return err
+}
+
+// InvalidateTags removes the keys associated with any of the tags
+func (c *Cache) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		tagKey := c.tagKey(tag)
+
+		keys, err := c.db.SMembers(ctx, tagKey)
+		if err != nil {
+			return err
+		}
+
+		// keys are stored with the prefix, and the tag set is removed with them
+		if _, err = c.db.Delete(ctx, append(keys, tagKey)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) store(ctx context.Context, key string, data []byte, ttl time.Duration, tags []string) error {
+	ttl = c.jitter(ttl)
+	fullKey := c.options.Prefix + key
+
+	if err := c.db.Set(ctx, fullKey, data, ttl); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if err := c.addToTag(ctx, tag, fullKey, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) storeLogged(ctx context.Context, key string, data []byte, ttl time.Duration, tags []string) {
+	if err := c.store(ctx, key, data, ttl, tags); err != nil {
+		logger.GetLogger().Errorf("Error writing the key %s to cache: %s", key, err)
+	}
+}
+
+// adds the key to the tag set, which lives at least as long as the key
+func (c *Cache) addToTag(ctx context.Context, tag, fullKey string, ttl time.Duration) error {
+	tagKey := c.tagKey(tag)
+
+	if _, err := c.db.SAdd(ctx, tagKey, fullKey); err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	current, err := c.db.TTL(ctx, tagKey)
+	if err != nil {
+		return err
+	}
+
+	// extend the expiration if it's shorter than ttl. A negative TTL means the
+	// set has no expiration, e.g. because it was just created; the exact value
+	// depends on the backend and its client version.
+	if current < ttl {
+		_, err = c.db.Expire(ctx, tagKey, ttl)
+	}
+	return err
+}
+
+func (c *Cache) tagKey(tag string) string {
+	return c.options.Prefix + "tag:" + tag
+}
+
+// adds a random fraction of ttl, up to the configured jitter
+func (c *Cache) jitter(ttl time.Duration) time.Duration {
+	if ttl <= 0 || c.options.Jitter <= 0 {
+		return ttl
+	}
+
+	c.randMutex.Lock()
+	defer c.randMutex.Unlock()
+	return ttl + time.Duration(c.rand.Float64()*c.options.Jitter*float64(ttl))
+}
+
+func (c *Cache) encode(value interface{}) ([]byte, error) {
+	data, err := c.options.Codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{markerValue}, data...), nil
+}
+
+func (c *Cache) decode(data []byte, dest interface{}) error {
+	if len(data) == 0 || data[0] == markerNotFound {
+		return ErrNotFound
+	}
+	return c.options.Codec.Unmarshal(data[1:], dest)
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/datasource"
+)
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func TestCodecs(t *testing.T) {
+	for name, codec := range map[string]Codec{"json": JSON, "gob": Gob, "msgpack": MsgPack} {
+		db := datasource.NewMemoryDB(0, 0)
+		c := New(db, Options{Codec: codec})
+
+		assert.NoError(t, c.Set(context.Background(), "u", user{ID: 1, Name: "ana"}, time.Minute), name)
+
+		var u user
+		assert.NoError(t, c.Get(context.Background(), "u", &u), name)
+		assert.Equal(t, user{ID: 1, Name: "ana"}, u, name)
+		assert.Equal(t, ErrCacheMiss, c.Get(context.Background(), "missing", &u), name)
+
+		db.Close()
+	}
+}
+
+func TestGetOrLoadSingleFlight(t *testing.T) {
+	db := datasource.NewMemoryDB(0, 0)
+	defer db.Close()
+	c := New(db, Options{})
+
+	var loads int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return user{ID: 7, Name: "bob"}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]user, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, c.GetOrLoad(context.Background(), "u", time.Minute, &results[i], loader))
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+	for _, u := range results {
+		assert.Equal(t, "bob", u.Name)
+	}
+
+	// cached now
+	var u user
+	assert.NoError(t, c.GetOrLoad(context.Background(), "u", time.Minute, &u, loader))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+}
+
+func TestNegativeCaching(t *testing.T) {
+	db := datasource.NewMemoryDB(0, 0)
+	defer db.Close()
+	c := New(db, Options{NegativeTTL: time.Minute})
+
+	var loads int
+	loader := func(ctx context.Context) (interface{}, error) {
+		loads++
+		return nil, ErrNotFound
+	}
+
+	var u user
+	assert.Equal(t, ErrNotFound, c.GetOrLoad(context.Background(), "u", time.Minute, &u, loader))
+	assert.Equal(t, ErrNotFound, c.GetOrLoad(context.Background(), "u", time.Minute, &u, loader))
+	assert.Equal(t, 1, loads)
+	assert.Equal(t, ErrNotFound, c.Get(context.Background(), "u", &u))
+}
+
+func TestInvalidateTags(t *testing.T) {
+	ctx := context.Background()
+	db := datasource.NewMemoryDB(0, 0)
+	defer db.Close()
+	c := New(db, Options{Prefix: "test:"})
+
+	c.Set(ctx, "u1", user{ID: 1}, time.Minute, "users")
+	c.Set(ctx, "u2", user{ID: 2}, time.Hour, "users", "admins")
+	c.Set(ctx, "p1", "product", time.Minute, "products")
+
+	ttl, _ := db.TTL(ctx, "test:tag:users")
+	assert.Equal(t, time.Hour, ttl)
+
+	assert.NoError(t, c.InvalidateTags(ctx, "users"))
+
+	var u user
+	assert.Equal(t, ErrCacheMiss, c.Get(ctx, "u1", &u))
+	assert.Equal(t, ErrCacheMiss, c.Get(ctx, "u2", &u))
+	var p string
+	assert.NoError(t, c.Get(ctx, "p1", &p))
+}
+
+// rawTTLDB returns the TTL sentinels as go-redis v8 does: -1ns if the key has
+// no expiration and -2ns if it doesn't exist
+type rawTTLDB struct {
+	*datasource.MemoryDB
+}
+
+func (db rawTTLDB) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := db.MemoryDB.TTL(ctx, key)
+	if ttl < 0 {
+		ttl /= time.Second
+	}
+	return ttl, err
+}
+
+func TestTagExpirationRawTTL(t *testing.T) {
+	ctx := context.Background()
+	db := datasource.NewMemoryDB(0, 0)
+	defer db.Close()
+	c := New(rawTTLDB{db}, Options{Prefix: "test:"})
+
+	c.Set(ctx, "u1", user{ID: 1}, time.Minute, "users")
+
+	ttl, _ := db.TTL(ctx, "test:tag:users")
+	assert.Equal(t, time.Minute, ttl)
+}
+
+func TestJitter(t *testing.T) {
+	c := New(nil, Options{Jitter: 0.1})
+	for i := 0; i < 100; i++ {
+		ttl := c.jitter(time.Minute)
+		assert.True(t, ttl >= time.Minute && ttl < 66*time.Second)
+	}
+	assert.Equal(t, time.Duration(0), c.jitter(0))
+}
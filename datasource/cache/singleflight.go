@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+)
+
+// returned to the callers waiting for a load that panicked
+var errLoadPanicked = errors.New("cache: load panicked")
+
+// call is a load in progress or completed
+type call struct {
+	wg   sync.WaitGroup
+	data []byte
+	err  error
+}
+
+// flightGroup deduplicates concurrent loads of the same key: the first caller
+// runs the load, and the rest wait for its result.
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[string]*call
+}
+
+// do runs fn once for all the concurrent calls with the same key. shared is
+// true if the result was obtained by another caller.
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) (data []byte, err error, shared bool) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, exists := g.calls[key]; exists {
+		g.mutex.Unlock()
+		c.wg.Wait()
+		return c.data, c.err, true
+	}
+
+	c := &call{err: errLoadPanicked}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mutex.Unlock()
+
+	// release the waiting callers even if fn panics
+	defer func() {
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		c.wg.Done()
+	}()
+
+	c.data, c.err = fn()
+	return c.data, c.err, false
+}
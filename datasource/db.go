@@ -1,10 +1,13 @@
 package datasource
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"sync"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/tuckyapps/lit-go-tools/database"
 	"github.com/tuckyapps/lit-go-tools/datasource/mysql"
 	"github.com/tuckyapps/lit-go-tools/datasource/postgresql"
 	"github.com/tuckyapps/lit-go-tools/datasource/redis"
@@ -15,6 +18,10 @@ import (
 var (
 	ErrNoDatabase         = errors.New("no database found with the specified name")
 	ErrDriverNotSupported = errors.New("database driver not supported")
+	ErrKeyNotFound        = redis.ErrKeyNotFound
+	ErrConnection         = redis.ErrConnection
+	ErrTimeout            = redis.ErrTimeout
+	ErrTypeMismatch       = redis.ErrTypeMismatch
 	syncInstance          = new(sync.Mutex)
 	instance              InMemoryDB
 )
@@ -28,29 +35,63 @@ type DBAccess interface {
 	Close() error
 	CanLock() bool
 	RandomFuncName() string
+	Dialect() database.Dialect
+	Writer() (*sqlx.DB, error)
+	Reader() (*sqlx.DB, error)
+	ReaderContext(ctx context.Context) (*sqlx.DB, error)
 }
 
 // Generic is the generic data access implementation for `DBAccess` interface.
 // Drivers currently supported:
 // - mysql
+// - postgres
 // - sqlite3
 //
+// All of them can be instrumented, see DBConfig.Instrumentation.
 type Generic struct {
-	db           *sqlx.DB
-	canLock      bool
-	randFuncName string
+	db       *sqlx.DB
+	replicas *replicaSet
+	driver   string
+	dialect  database.Dialect
 }
 
-// New configures the datasources
+// New configures the datasources. If config has replicas, they're used by Reader.
+// Failing to open a replica is fatal, as a wrong configuration would otherwise
+// go unnoticed: the databases already opened are closed, and New returns nil
+// and the error.
 func (g *Generic) New(config DBConfig) (db *sqlx.DB, err error) {
+	if db, err = g.newPrimary(config); err != nil {
+		// opened, but the ping failed
+		if db != nil {
+			db.Close()
+		}
+		return nil, err
+	}
+
+	if g.replicas != nil {
+		g.replicas.close()
+		g.replicas = nil
+	}
+	if len(config.Replicas) > 0 {
+		if g.replicas, err = newReplicaSet(config); err != nil {
+			g.Close()
+			g.db, g.driver, g.dialect = nil, "", nil
+			return nil, err
+		}
+	}
+	return
+}
+
+func (g *Generic) newPrimary(config DBConfig) (db *sqlx.DB, err error) {
 	switch config.Driver {
 
 	case "postgres":
-		db, err = postgresql.Init(
-			config.Address,
-			config.Username,
-			config.Password,
-			config.DBName)
+		var options postgresql.Options
+		if options, err = postgresOptions(config, config.Address); err != nil {
+			return
+		}
+
+		db, err = postgresql.Open(options)
 		if err == nil {
 			db.SetMaxOpenConns(config.MaxOpenConnections)
 			db.SetMaxIdleConns(config.MaxIdleConnections)
@@ -70,15 +111,16 @@ func (g *Generic) New(config DBConfig) (db *sqlx.DB, err error) {
 		}
 
 		g.db = db
-		g.canLock = true
-		g.randFuncName = postgresql.RandFuncName()
+		g.driver = config.Driver
+		g.dialect = database.Postgres
 
 	case "mysql":
-		db, err = mysql.Init(
-			config.Address,
-			config.Username,
-			config.Password,
-			config.DBName)
+		var options mysql.Options
+		if options, err = mysqlOptions(config, config.Address); err != nil {
+			return
+		}
+
+		db, err = mysql.Open(options)
 		if err == nil {
 			db.SetMaxOpenConns(config.MaxOpenConnections)
 			db.SetMaxIdleConns(config.MaxIdleConnections)
@@ -98,11 +140,19 @@ func (g *Generic) New(config DBConfig) (db *sqlx.DB, err error) {
 		}
 
 		g.db = db
-		g.canLock = true
-		g.randFuncName = mysql.RandFuncName()
+		g.driver = config.Driver
+		g.dialect = database.MySQL
 
 	case "sqlite3":
-		db, err = sqlite3.Init(config.DBName)
+		var driverName string
+		if driverName, err = sqlDriverName(config); err != nil {
+			return
+		}
+
+		db, err = sqlite3.Open(driverName, config.DBName)
+		if err != nil {
+			return nil, err
+		}
 
 		// ping DB to check if it's OK
 		if err = db.Ping(); err != nil {
@@ -115,8 +165,8 @@ func (g *Generic) New(config DBConfig) (db *sqlx.DB, err error) {
 		}
 
 		g.db = db
-		g.canLock = false
-		g.randFuncName = sqlite3.RandFuncName()
+		g.driver = config.Driver
+		g.dialect = database.SQLite3
 
 	default:
 		return nil, ErrDriverNotSupported
@@ -135,52 +185,153 @@ func (g *Generic) Get() (db *sqlx.DB, err error) {
 	return
 }
 
+// Writer returns the primary DB instance, used for writes
+func (g *Generic) Writer() (*sqlx.DB, error) {
+	return g.Get()
+}
+
+// Reader returns a healthy replica, or the primary if there are none
+func (g *Generic) Reader() (*sqlx.DB, error) {
+	return g.ReaderContext(context.Background())
+}
+
+// ReaderContext is like Reader, but returns the primary if ctx was created with
+// WithPrimary
+func (g *Generic) ReaderContext(ctx context.Context) (*sqlx.DB, error) {
+	if g.replicas != nil && !usePrimary(ctx) {
+		if db := g.replicas.pick(); db != nil {
+			return db, nil
+		}
+	}
+	return g.Get()
+}
+
+// Replicas returns the state of the replicas
+func (g *Generic) Replicas() []ReplicaStatus {
+	if g.replicas == nil {
+		return nil
+	}
+	return g.replicas.status()
+}
+
 // Close should be called when the server ends the execution,
 // so connection are gracefully released
 func (g *Generic) Close() (err error) {
+	if g.replicas != nil {
+		err = g.replicas.close()
+		g.replicas = nil
+	}
 	if g.db != nil {
-		err = g.db.Close()
+		if errClose := g.db.Close(); errClose != nil {
+			err = errClose
+		}
 	}
 	return
 }
 
 // CanLock returns true if the current driver supportes locking
 func (g *Generic) CanLock() (lock bool) {
-	if g != nil {
-		lock = g.canLock
+	if d := g.Dialect(); d != nil {
+		lock = d.SupportsAdvisoryLocks()
 	}
 	return
 }
 
 // RandomFuncName returns the driver's RANDOM name
 func (g *Generic) RandomFuncName() (fName string) {
+	if d := g.Dialect(); d != nil {
+		fName = d.RandomFunc()
+	}
+	return
+}
+
+// Dialect returns the SQL dialect of the driver, or nil if no database was
+// configured
+func (g *Generic) Dialect() (d database.Dialect) {
 	if g != nil {
-		fName = g.randFuncName
+		d = g.dialect
 	}
 	return
 }
 
-//BuildNewInMemoryConnection returns an in-memory database connection
+// Locker returns a Locker using the advisory locks of the database. It returns
+// ErrLockNotSupported if the driver can't lock (see CanLock).
+func (g *Generic) Locker(options LockerOptions) (Locker, error) {
+	db, err := g.Get()
+	if err != nil {
+		return nil, err
+	}
+	if !g.CanLock() {
+		return nil, ErrLockNotSupported
+	}
+	return NewSQLLocker(db, g.driver, options)
+}
+
+// BuildNewInMemoryConnection returns an in-memory database connection
 func BuildNewInMemoryConnection(address string, password string) InMemoryDB {
-	if instance == nil {
+	db, _ := BuildInMemoryConnection(InMemoryDBConfig{Address: address, Password: password})
+	return db
+}
 
-		syncInstance.Lock()
-		defer syncInstance.Unlock()
+// BuildInMemoryConnection returns the in-memory database connection, creating
+// it with config on first call. The connection is shared, so later calls return
+// the same instance until ResetInMemoryDb is called.
+func BuildInMemoryConnection(config InMemoryDBConfig) (InMemoryDB, error) {
+	syncInstance.Lock()
+	defer syncInstance.Unlock()
 
-		if instance == nil {
-			instance = &redis.Redis{
-				Address:  address,
-				Password: password,
-			}
+	if instance == nil {
+		db, err := NewInMemoryDB(config)
+		if err != nil {
+			return nil, err
 		}
+		instance = db
+	}
+	return instance, nil
+}
 
+// NewInMemoryDB creates an in-memory database using the configured driver. The
+// caller is responsible for closing it.
+func NewInMemoryDB(config InMemoryDBConfig) (InMemoryDB, error) {
+	switch config.Driver {
+	case "", InMemoryDriverRedis:
+		return newRedis(config), nil
+	case InMemoryDriverMemory:
+		return NewMemoryDB(config.MaxEntries, config.CleanupInterval), nil
+	default:
+		return nil, ErrDriverNotSupported
 	}
-	return instance
 }
 
-// ResetInMemoryDb is used to erase instance.
+func newRedis(config InMemoryDBConfig) *redis.Redis {
+	tlsConfig := config.TLSConfig
+	if config.TLS && tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+
+	return &redis.Redis{
+		Address:      config.Address,
+		Password:     config.Password,
+		DB:           config.DB,
+		TLSConfig:    tlsConfig,
+		DialTimeout:  config.DialTimeout,
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		PoolSize:     config.PoolSize,
+		MinIdleConns: config.MinIdleConns,
+		PoolTimeout:  config.PoolTimeout,
+		IdleTimeout:  config.IdleTimeout,
+		MaxRetries:   config.MaxRetries,
+	}
+}
+
+// ResetInMemoryDb is used to erase instance. Its connections are closed.
 func ResetInMemoryDb() {
 	syncInstance.Lock()
 	defer syncInstance.Unlock()
+
+	if instance != nil {
+		instance.Close()
+	}
 	instance = nil
 }
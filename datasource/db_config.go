@@ -1,9 +1,38 @@
 package datasource
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
 	"time"
+
+	"github.com/tuckyapps/lit-go-tools/datasource/mysql"
+	"github.com/tuckyapps/lit-go-tools/datasource/postgresql"
+)
+
+// TLS modes
+const (
+	TLSDisable    = "disable"
+	TLSRequire    = "require"     // encrypted, without verifying the server certificate
+	TLSVerifyCA   = "verify-ca"   // the certificate must be signed by the CA
+	TLSVerifyFull = "verify-full" // like verify-ca, and the host name must match
 )
 
+// ErrInvalidCA is returned when the CA file doesn't contain any certificate
+var ErrInvalidCA = errors.New("no certificates found in the CA file")
+
+// TLSConfig holds the TLS settings of the connections to a database
+type TLSConfig struct {
+	Mode     string
+	CAFile   string
+	CertFile string // client certificate, optional
+	KeyFile  string
+
+	// ServerName overrides the host name verified by MySQL connections
+	ServerName string
+}
+
 // DBConfig holds the configuration required to initialize a data source
 type DBConfig struct {
 	DBName             string
@@ -14,4 +43,114 @@ type DBConfig struct {
 	MaxOpenConnections int
 	MaxIdleConnections int
 	ConnectionLifetime time.Duration
+
+	// TLS configures encrypted connections; nil uses the driver default
+	TLS *TLSConfig
+
+	// timeouts of the connections; PostgreSQL only supports ConnectTimeout
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+
+	// Params are added to the DSN, e.g. charset and loc for MySQL, or
+	// application_name for PostgreSQL
+	Params map[string]string
+
+	// Instrumentation wraps the driver: "none" (default), "log", "newrelic"
+	// (importing nrdatasource), "otel" (importing oteldatasource), or any other
+	// added with RegisterInstrumentation
+	Instrumentation string
+
+	// Replicas are the addresses of the read replicas, which use the same
+	// credentials and database name as the primary
+	Replicas []string
+
+	// ReplicaSelection is "round-robin" (default) or "least-latency"
+	ReplicaSelection string
+
+	// HealthCheckInterval is the time between the health checks of the
+	// replicas (10 seconds by default)
+	HealthCheckInterval time.Duration
+}
+
+// returns the MySQL options of config, connecting to address
+func mysqlOptions(config DBConfig, address string) (options mysql.Options, err error) {
+	driverName, err := sqlDriverName(config)
+	if err != nil {
+		return
+	}
+
+	options = mysql.Options{
+		DriverName:     driverName,
+		Address:        address,
+		Username:       config.Username,
+		Password:       config.Password,
+		DBName:         config.DBName,
+		ConnectTimeout: config.ConnectTimeout,
+		ReadTimeout:    config.ReadTimeout,
+		WriteTimeout:   config.WriteTimeout,
+		Params:         config.Params,
+	}
+
+	if config.TLS != nil && config.TLS.Mode != TLSDisable {
+		options.TLSConfig, err = config.TLS.build()
+	}
+	return
+}
+
+// returns the PostgreSQL options of config, connecting to address
+func postgresOptions(config DBConfig, address string) (postgresql.Options, error) {
+	driverName, err := sqlDriverName(config)
+	if err != nil {
+		return postgresql.Options{}, err
+	}
+
+	options := postgresql.Options{
+		DriverName:     driverName,
+		Address:        address,
+		Username:       config.Username,
+		Password:       config.Password,
+		DBName:         config.DBName,
+		ConnectTimeout: config.ConnectTimeout,
+		Params:         config.Params,
+	}
+
+	if config.TLS != nil {
+		options.SSLMode = config.TLS.Mode
+		options.SSLRootCert = config.TLS.CAFile
+		options.SSLCert = config.TLS.CertFile
+		options.SSLKey = config.TLS.KeyFile
+	}
+	return options, nil
+}
+
+// builds the crypto/tls configuration, loading the certificates. verify-ca is
+// verified like verify-full, since the MySQL driver always checks the host name.
+func (tc *TLSConfig) build() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         tc.ServerName,
+		InsecureSkipVerify: tc.Mode == TLSRequire,
+	}
+
+	if tc.CAFile != "" {
+		pem, err := ioutil.ReadFile(tc.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, ErrInvalidCA
+		}
+	}
+
+	if tc.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
 }
@@ -0,0 +1,64 @@
+package datasource
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLOptionsTLS(t *testing.T) {
+	config := DBConfig{Driver: "mysql", TLS: &TLSConfig{Mode: TLSRequire}}
+	options, err := mysqlOptions(config, "db.local:3306")
+	assert.Nil(t, err)
+	assert.Equal(t, "db.local:3306", options.Address)
+	assert.True(t, options.TLSConfig.InsecureSkipVerify)
+
+	config.TLS.Mode = TLSDisable
+	options, err = mysqlOptions(config, "db.local:3306")
+	assert.Nil(t, err)
+	assert.Nil(t, options.TLSConfig)
+
+	config.TLS = nil
+	options, err = mysqlOptions(config, "db.local:3306")
+	assert.Nil(t, err)
+	assert.Nil(t, options.TLSConfig)
+}
+
+func TestMySQLOptionsInvalidCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.Nil(t, ioutil.WriteFile(caFile, []byte("not a certificate"), 0600))
+
+	config := DBConfig{Driver: "mysql", TLS: &TLSConfig{Mode: TLSVerifyFull, CAFile: caFile}}
+	_, err = mysqlOptions(config, "db.local:3306")
+	assert.Equal(t, ErrInvalidCA, err)
+
+	config.TLS.CAFile = filepath.Join(dir, "missing.pem")
+	_, err = mysqlOptions(config, "db.local:3306")
+	assert.NotNil(t, err)
+}
+
+func TestPostgresOptions(t *testing.T) {
+	config := DBConfig{
+		Driver:   "postgres",
+		Username: "app",
+		DBName:   "main",
+		TLS:      &TLSConfig{Mode: TLSVerifyCA, CAFile: "ca.pem", CertFile: "client.pem", KeyFile: "client.key"},
+		Params:   map[string]string{"application_name": "api"},
+	}
+
+	options, err := postgresOptions(config, "replica:5432")
+	assert.Nil(t, err)
+	assert.Equal(t, "replica:5432", options.Address)
+	assert.Equal(t, "verify-ca", options.SSLMode)
+	assert.Equal(t, "ca.pem", options.SSLRootCert)
+	assert.Equal(t, "client.pem", options.SSLCert)
+	assert.Equal(t, "client.key", options.SSLKey)
+	assert.Equal(t, "api", options.Params["application_name"])
+}
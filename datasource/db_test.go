@@ -0,0 +1,58 @@
+package datasource
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/database"
+)
+
+func TestGenericDialect(t *testing.T) {
+	var g *Generic
+	assert.Nil(t, g.Dialect())
+	assert.False(t, g.CanLock())
+	assert.Equal(t, "", g.RandomFuncName())
+
+	g = new(Generic)
+	_, err := g.New(DBConfig{Driver: "sqlite3", DBName: ":memory:"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer g.Close()
+
+	assert.Equal(t, database.SQLite3, g.Dialect())
+	assert.False(t, g.CanLock())
+	assert.Equal(t, "random()", g.RandomFuncName())
+
+	// the upsert works in the database
+	db, _ := g.Get()
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	assert.NoError(t, err)
+	upsert := g.Dialect().Upsert("users", []string{"id", "name"}, []string{"id"}, []string{"name"})
+	for _, name := range []string{"ann", "bob"} {
+		_, err = db.Exec(upsert, 1, name)
+		assert.NoError(t, err)
+	}
+
+	var name string
+	assert.NoError(t, db.Get(&name, "SELECT name FROM users WHERE id = 1"))
+	assert.Equal(t, "bob", name)
+}
+
+func TestGenericReplicaFailure(t *testing.T) {
+	g := new(Generic)
+	db, err := g.New(DBConfig{
+		Driver:          "sqlite3",
+		DBName:          ":memory:",
+		Replicas:        []string{":memory:"},
+		Instrumentation: registerReplicaFailure(),
+	})
+	assert.EqualError(t, err, "replica failure")
+	assert.Nil(t, db)
+
+	_, err = g.Get()
+	assert.Equal(t, ErrNoDatabase, err)
+	assert.Nil(t, g.Dialect())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&sqliteCounting.open))
+}
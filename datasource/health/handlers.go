@@ -0,0 +1,69 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+// LivenessHTTP returns a handler that always responds 200 while the process
+// runs, with the report of the checks in the body
+func (c *Checker) LivenessHTTP() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, c.Report())
+	})
+}
+
+// ReadinessHTTP returns a handler that responds 200 if the required checks are
+// up, or 503 otherwise, with the report of the checks in the body
+func (c *Checker) ReadinessHTTP() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Report()
+		writeReport(w, readinessCode(report), report)
+	})
+}
+
+// Liveness is the gin version of LivenessHTTP
+func (c *Checker) Liveness() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, c.Report())
+	}
+}
+
+// Readiness is the gin version of ReadinessHTTP
+func (c *Checker) Readiness() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report := c.Report()
+		ctx.JSON(readinessCode(report), report)
+	}
+}
+
+// LivenessEcho is the Echo version of LivenessHTTP
+func (c *Checker) LivenessEcho() echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, c.Report())
+	}
+}
+
+// ReadinessEcho is the Echo version of ReadinessHTTP
+func (c *Checker) ReadinessEcho() echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		report := c.Report()
+		return ctx.JSON(readinessCode(report), report)
+	}
+}
+
+func readinessCode(report Report) int {
+	if report.Ready() {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,315 @@
+// Package health checks the dependencies of a service periodically, the SQL
+// datasources and the InMemoryDB, and exposes their state through liveness and
+// readiness handlers for gin, Echo and net/http. The connection pool stats of
+// the datasources are exported as expvar and Prometheus metrics.
+//
+//	checker := health.New(health.Options{})
+//	checker.AddDB("main", db)
+//	checker.AddInMemoryDB("redis", inMemoryDB)
+//	checker.Start()
+//	defer checker.Close()
+//
+//	r.GET("/health/live", checker.Liveness())
+//	r.GET("/health/ready", checker.Readiness())
+//	r.GET("/metrics", gin.WrapH(checker.MetricsHandler()))
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tuckyapps/lit-go-tools/datasource"
+)
+
+// Status values
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusUnknown  = "unknown"  // not checked yet
+	StatusDegraded = "degraded" // only optional checks are down
+)
+
+// Default options
+const (
+	DefaultInterval = 10 * time.Second
+	DefaultTimeout  = 2 * time.Second
+)
+
+// Check returns an error if the dependency isn't available
+type Check func(ctx context.Context) error
+
+// returned by the checks of the registry databases that aren't open yet, which
+// are left out of the report
+var errNotOpen = errors.New("database not open")
+
+// Options holds the configuration of a Checker
+type Options struct {
+	// Interval is the time between checks, 10 seconds by default
+	Interval time.Duration
+
+	// Timeout is the max duration of each check, 2 seconds by default
+	Timeout time.Duration
+}
+
+// CheckStatus is the result of the last run of a check
+type CheckStatus struct {
+	Status    string        `json:"status"`
+	Optional  bool          `json:"optional,omitempty"`
+	Latency   time.Duration `json:"-"`
+	LatencyMs float64       `json:"latency_ms"`
+	Error     string        `json:"error,omitempty"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// Report is the state of all the checks
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckStatus `json:"checks"`
+}
+
+// Ready returns true if no required check is down or unknown
+func (r Report) Ready() bool {
+	return r.Status == StatusUp || r.Status == StatusDegraded
+}
+
+type check struct {
+	name     string
+	fn       Check
+	optional bool
+	status   CheckStatus
+	skipped  bool // not reported, see errNotOpen
+}
+
+// Checker runs the checks periodically, keeping their last status. It's safe
+// for concurrent use.
+type Checker struct {
+	options Options
+
+	mutex  sync.RWMutex
+	checks []*check
+	dbs    map[string]func() (*sqlx.DB, error)
+
+	startOnce sync.Once
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// New creates a Checker without checks
+func New(options Options) *Checker {
+	if options.Interval <= 0 {
+		options.Interval = DefaultInterval
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+
+	return &Checker{
+		options: options,
+		dbs:     make(map[string]func() (*sqlx.DB, error)),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Add adds a required check: the service isn't ready while it's down
+func (c *Checker) Add(name string, fn Check) {
+	c.add(name, fn, false)
+}
+
+// AddOptional adds a check that doesn't affect the readiness; the status is
+// degraded while it's down
+func (c *Checker) AddOptional(name string, fn Check) {
+	c.add(name, fn, true)
+}
+
+func (c *Checker) add(name string, fn Check, optional bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.checks = append(c.checks, &check{
+		name:     name,
+		fn:       fn,
+		optional: optional,
+		status:   CheckStatus{Status: StatusUnknown, Optional: optional},
+	})
+}
+
+// AddDB adds a required check that pings db, and exports its pool stats
+func (c *Checker) AddDB(name string, db *sqlx.DB) {
+	c.addDB(name, func() (*sqlx.DB, error) { return db, nil })
+}
+
+// AddGeneric is like AddDB, for the database of g
+func (c *Checker) AddGeneric(name string, g *datasource.Generic) {
+	c.addDB(name, g.Get)
+}
+
+// AddRegistry calls AddDB for each database of the registry, using their names.
+// The databases aren't opened by the checks or the metrics: they're only
+// reported once the application opens them.
+func (c *Checker) AddRegistry(r *datasource.Registry) {
+	for _, name := range r.Names() {
+		name := name
+		c.addDB(name, func() (*sqlx.DB, error) {
+			if db, open := r.Lookup(name); open {
+				return db, nil
+			}
+			return nil, errNotOpen
+		})
+	}
+}
+
+func (c *Checker) addDB(name string, get func() (*sqlx.DB, error)) {
+	c.mutex.Lock()
+	c.dbs[name] = get
+	c.mutex.Unlock()
+
+	c.Add(name, func(ctx context.Context) error {
+		db, err := get()
+		if err != nil {
+			return err
+		}
+		return db.PingContext(ctx)
+	})
+}
+
+// AddInMemoryDB adds a required check that pings db
+func (c *Checker) AddInMemoryDB(name string, db datasource.InMemoryDB) {
+	c.Add(name, db.Ping)
+}
+
+// Start runs the checks, and keeps running them periodically until Close is
+// called. It returns after the first run.
+func (c *Checker) Start() {
+	c.startOnce.Do(func() {
+		c.CheckNow(context.Background())
+		go c.loop()
+	})
+}
+
+// Close stops the periodic checks
+func (c *Checker) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.startOnce.Do(func() { close(c.done) })
+	})
+	<-c.done
+}
+
+func (c *Checker) loop() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.CheckNow(context.Background())
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// CheckNow runs all the checks concurrently, and returns the report
+func (c *Checker) CheckNow(ctx context.Context) Report {
+	c.mutex.RLock()
+	checks := append([]*check(nil), c.checks...)
+	c.mutex.RUnlock()
+
+	var wg sync.WaitGroup
+	results := make([]CheckStatus, len(checks))
+	skipped := make([]bool, len(checks))
+	for i, ch := range checks {
+		wg.Add(1)
+		go func(i int, ch *check) {
+			defer wg.Done()
+			results[i], skipped[i] = c.run(ctx, ch)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	c.mutex.Lock()
+	for i, ch := range checks {
+		ch.status = results[i]
+		ch.skipped = skipped[i]
+	}
+	c.mutex.Unlock()
+
+	return c.Report()
+}
+
+// runs the check; skipped is true if it returned errNotOpen
+func (c *Checker) run(ctx context.Context, ch *check) (status CheckStatus, skipped bool) {
+	ctx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			status = CheckStatus{Status: StatusDown, Error: "check panicked"}
+		}
+		status.Optional = ch.optional
+		status.Latency = time.Since(start)
+		status.LatencyMs = float64(status.Latency) / float64(time.Millisecond)
+		status.CheckedAt = start
+	}()
+
+	if err := ch.fn(ctx); err == errNotOpen {
+		return CheckStatus{Status: StatusUnknown}, true
+	} else if err != nil {
+		return CheckStatus{Status: StatusDown, Error: err.Error()}, false
+	}
+	return CheckStatus{Status: StatusUp}, false
+}
+
+// Report returns the status of the last run of the checks
+func (c *Checker) Report() Report {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckStatus, len(c.checks))}
+	for _, ch := range c.checks {
+		if ch.skipped {
+			continue
+		}
+		report.Checks[ch.name] = ch.status
+
+		switch {
+		case ch.status.Status == StatusUp:
+		case !ch.optional:
+			report.Status = StatusDown
+		case report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// returns the databases added with AddDB, sorted by name, skipping the ones
+// that can't be opened and the registry ones not open yet
+func (c *Checker) databases() (names []string, dbs []*sqlx.DB) {
+	c.mutex.RLock()
+	getters := make(map[string]func() (*sqlx.DB, error), len(c.dbs))
+	for name, get := range c.dbs {
+		names = append(names, name)
+		getters[name] = get
+	}
+	c.mutex.RUnlock()
+	sort.Strings(names)
+
+	var opened []string
+	for _, name := range names {
+		if db, err := getters[name](); err == nil && db != nil {
+			opened = append(opened, name)
+			dbs = append(dbs, db)
+		}
+	}
+	return opened, dbs
+}
@@ -0,0 +1,197 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/datasource"
+	"github.com/tuckyapps/lit-go-tools/datasource/sqlite3"
+)
+
+func TestChecker(t *testing.T) {
+	db, err := sqlite3.Init(":memory:")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	memory := datasource.NewMemoryDB(0, 0)
+	defer memory.Close()
+
+	c := New(Options{Interval: time.Hour})
+	c.AddDB("main", db)
+	c.AddInMemoryDB("cache", memory)
+
+	var searchErr error
+	c.AddOptional("search", func(ctx context.Context) error { return searchErr })
+
+	report := c.Report()
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, StatusUnknown, report.Checks["main"].Status)
+
+	c.Start()
+	defer c.Close()
+
+	report = c.Report()
+	assert.Equal(t, StatusUp, report.Status)
+	assert.True(t, report.Ready())
+	assert.Equal(t, StatusUp, report.Checks["main"].Status)
+	assert.Equal(t, StatusUp, report.Checks["cache"].Status)
+	assert.False(t, report.Checks["main"].CheckedAt.IsZero())
+
+	searchErr = errors.New("search unavailable")
+	report = c.CheckNow(context.Background())
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.True(t, report.Ready())
+	assert.Equal(t, "search unavailable", report.Checks["search"].Error)
+	assert.True(t, report.Checks["search"].Optional)
+
+	memory.Close()
+	report = c.CheckNow(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusDown, report.Checks["cache"].Status)
+}
+
+func TestCheckTimeoutAndPanic(t *testing.T) {
+	c := New(Options{Timeout: 10 * time.Millisecond})
+	c.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c.Add("broken", func(ctx context.Context) error { panic("boom") })
+
+	report := c.CheckNow(context.Background())
+	assert.Equal(t, StatusDown, report.Checks["slow"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+	assert.Equal(t, "check panicked", report.Checks["broken"].Error)
+
+	// closing a checker that wasn't started doesn't block
+	c.Close()
+}
+
+func TestHandlers(t *testing.T) {
+	c := New(Options{})
+	failing := errors.New("unavailable")
+	c.Add("dependency", func(ctx context.Context) error { return failing })
+	c.CheckNow(context.Background())
+
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.GET("/live", c.Liveness())
+	g.GET("/ready", c.Readiness())
+
+	e := echo.New()
+	e.GET("/live", c.LivenessEcho())
+	e.GET("/ready", c.ReadinessEcho())
+
+	mux := http.NewServeMux()
+	mux.Handle("/live", c.LivenessHTTP())
+	mux.Handle("/ready", c.ReadinessHTTP())
+
+	for name, handler := range map[string]http.Handler{"gin": g, "echo": e, "http": mux} {
+		for _, test := range []struct {
+			path string
+			code int
+		}{
+			{"/live", http.StatusOK},
+			{"/ready", http.StatusServiceUnavailable},
+		} {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+			assert.Equal(t, test.code, w.Code, name+test.path)
+
+			var report Report
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report), name+test.path)
+			assert.Equal(t, StatusDown, report.Status, name+test.path)
+			assert.Equal(t, "unavailable", report.Checks["dependency"].Error, name+test.path)
+		}
+	}
+
+	failing = nil
+	c.CheckNow(context.Background())
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMetrics(t *testing.T) {
+	db, err := sqlite3.Init(":memory:")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(5)
+
+	c := New(Options{})
+	c.AddDB("main", db)
+	c.CheckNow(context.Background())
+
+	w := httptest.NewRecorder()
+	c.MetricsHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, "# TYPE datasource_open_connections gauge\n")
+	assert.Contains(t, body, `datasource_max_open_connections{db="main"} 5`)
+	assert.Contains(t, body, `datasource_open_connections{db="main"} 1`)
+	assert.Contains(t, body, `datasource_idle_connections{db="main"} 1`)
+	assert.Contains(t, body, "# TYPE datasource_wait_count_total counter\n")
+	assert.Contains(t, body, `datasource_up{check="main"} 1`)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"))
+
+	c.PublishExpvar("health_test_db")
+	var vars map[string]DBStats
+	assert.NoError(t, json.Unmarshal([]byte(expvar.Get("health_test_db").String()), &vars))
+	assert.Equal(t, 5, vars["main"].MaxOpenConnections)
+	assert.Equal(t, 1, vars["main"].OpenConnections)
+}
+
+func TestRegistryNotOpened(t *testing.T) {
+	r := datasource.NewRegistry()
+	r.Register("main", datasource.DBConfig{Driver: "sqlite3", DBName: ":memory:"})
+	r.Register("lazy", datasource.DBConfig{Driver: "sqlite3", DBName: ":memory:"})
+	defer r.CloseAll()
+
+	if _, err := r.Get("main"); !assert.NoError(t, err) {
+		return
+	}
+
+	c := New(Options{})
+	c.AddRegistry(r)
+	report := c.CheckNow(context.Background())
+
+	// the databases not opened by the application aren't opened nor reported
+	assert.True(t, report.Ready())
+	assert.Equal(t, []string{"main"}, checkNames(report))
+	stats := c.Stats()
+	assert.Contains(t, stats, "main")
+	assert.NotContains(t, stats, "lazy")
+	_, open := r.Lookup("lazy")
+	assert.False(t, open)
+
+	if _, err := r.Get("lazy"); !assert.NoError(t, err) {
+		return
+	}
+	report = c.CheckNow(context.Background())
+	assert.Equal(t, []string{"lazy", "main"}, checkNames(report))
+	assert.Equal(t, StatusUp, report.Checks["lazy"].Status)
+}
+
+func checkNames(report Report) []string {
+	var names []string
+	for name := range report.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
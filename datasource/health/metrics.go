@@ -0,0 +1,143 @@
+package health
+
+import (
+	"bufio"
+	"database/sql"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DBStats are the connection pool stats of a database, as exported by expvar
+type DBStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// Stats returns the connection pool stats of the databases added with AddDB,
+// AddGeneric and AddRegistry, by name
+func (c *Checker) Stats() map[string]sql.DBStats {
+	names, dbs := c.databases()
+
+	stats := make(map[string]sql.DBStats, len(names))
+	for i, name := range names {
+		stats[name] = dbs[i].Stats()
+	}
+	return stats
+}
+
+// PublishExpvar publishes the connection pool stats under name, so they're
+// served by expvar's /debug/vars. It panics if name is already published.
+func (c *Checker) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		vars := make(map[string]DBStats)
+		for db, s := range c.Stats() {
+			vars[db] = DBStats{
+				MaxOpenConnections: s.MaxOpenConnections,
+				OpenConnections:    s.OpenConnections,
+				InUse:              s.InUse,
+				Idle:               s.Idle,
+				WaitCount:          s.WaitCount,
+				WaitDurationMs:     s.WaitDuration.Milliseconds(),
+				MaxIdleClosed:      s.MaxIdleClosed,
+				MaxLifetimeClosed:  s.MaxLifetimeClosed,
+			}
+		}
+		return vars
+	}))
+}
+
+// a metric in the Prometheus text format
+type metric struct {
+	name  string
+	kind  string // gauge or counter
+	help  string
+	label string
+	value func(s sql.DBStats) float64
+}
+
+var dbMetrics = []metric{
+	{"datasource_max_open_connections", "gauge", "Maximum number of open connections.", "db",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	{"datasource_open_connections", "gauge", "Number of established connections, in use or idle.", "db",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+	{"datasource_in_use_connections", "gauge", "Number of connections in use.", "db",
+		func(s sql.DBStats) float64 { return float64(s.InUse) }},
+	{"datasource_idle_connections", "gauge", "Number of idle connections.", "db",
+		func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	{"datasource_wait_count_total", "counter", "Number of connections waited for.", "db",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+	{"datasource_wait_duration_seconds_total", "counter", "Time blocked waiting for a connection.", "db",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	{"datasource_max_idle_closed_total", "counter", "Connections closed due to MaxIdleConnections.", "db",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+	{"datasource_max_lifetime_closed_total", "counter", "Connections closed due to ConnectionLifetime.", "db",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+// MetricsHandler returns a handler that serves the connection pool stats and
+// the status of the checks in the Prometheus text format
+func (c *Checker) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		buf := bufio.NewWriter(w)
+		c.writeMetrics(buf)
+		buf.Flush()
+	})
+}
+
+func (c *Checker) writeMetrics(w *bufio.Writer) {
+	names, dbs := c.databases()
+	stats := make([]sql.DBStats, len(dbs))
+	for i, db := range dbs {
+		stats[i] = db.Stats()
+	}
+
+	for _, m := range dbMetrics {
+		writeHeader(w, m.name, m.kind, m.help)
+		for i, name := range names {
+			writeSample(w, m.name, m.label, name, m.value(stats[i]))
+		}
+	}
+
+	report := c.Report()
+	checks := make([]string, 0, len(report.Checks))
+	for name := range report.Checks {
+		checks = append(checks, name)
+	}
+	sort.Strings(checks)
+
+	writeHeader(w, "datasource_up", "gauge", "1 if the last check succeeded.")
+	for _, name := range checks {
+		up := 0.0
+		if report.Checks[name].Status == StatusUp {
+			up = 1
+		}
+		writeSample(w, "datasource_up", "check", name, up)
+	}
+
+	writeHeader(w, "datasource_check_latency_seconds", "gauge", "Duration of the last check.")
+	for _, name := range checks {
+		writeSample(w, "datasource_check_latency_seconds", "check", name, report.Checks[name].Latency.Seconds())
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeSample(w *bufio.Writer, name, label, labelValue string, value float64) {
+	fmt.Fprintf(w, "%s{%s=\"%s\"} %s\n", name, label, labelEscaper.Replace(labelValue),
+		strconv.FormatFloat(value, 'g', -1, 64))
+}
@@ -1,9 +1,92 @@
 package datasource
 
-import "time"
+import (
+	"context"
+	"crypto/tls"
+	"time"
+)
 
-// InMemoryDB declares Set and Get operations for redis.
+// InMemoryDB declares the operations of an in-memory database, like redis. The
+// operations are cancelled when ctx is done. Errors can be told apart with
+// errors.Is: ErrKeyNotFound for missing keys, and ErrConnection, ErrTimeout or
+// ErrTypeMismatch for driver failures.
 type InMemoryDB interface {
-	Set(key string, value interface{}, expiration time.Duration) error
-	Get(key string) ([]byte, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
+	Delete(ctx context.Context, keys ...string) (int64, error)
+	Exists(ctx context.Context, keys ...string) (int64, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) (bool, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Incr(ctx context.Context, key string) (int64, error)
+	IncrBy(ctx context.Context, key string, value int64) (int64, error)
+	Decr(ctx context.Context, key string) (int64, error)
+	DecrBy(ctx context.Context, key string, value int64) (int64, error)
+	MGet(ctx context.Context, keys ...string) ([][]byte, error)
+	MSet(ctx context.Context, values map[string]interface{}) error
+
+	// atomic operations that only apply if the key has the value
+	CompareAndDelete(ctx context.Context, key string, value interface{}) (bool, error)
+	CompareAndExpire(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
+
+	// hashes
+	HSet(ctx context.Context, key, field string, value interface{}) error
+	HMSet(ctx context.Context, key string, fields map[string]interface{}) error
+	HGet(ctx context.Context, key, field string) ([]byte, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HExists(ctx context.Context, key, field string) (bool, error)
+	HDel(ctx context.Context, key string, fields ...string) (int64, error)
+
+	// lists
+	LPush(ctx context.Context, key string, values ...interface{}) (int64, error)
+	RPush(ctx context.Context, key string, values ...interface{}) (int64, error)
+	LPop(ctx context.Context, key string) ([]byte, error)
+	RPop(ctx context.Context, key string) ([]byte, error)
+	LRange(ctx context.Context, key string, start, stop int64) ([]string, error)
+	LLen(ctx context.Context, key string) (int64, error)
+
+	// sets
+	SAdd(ctx context.Context, key string, members ...interface{}) (int64, error)
+	SRem(ctx context.Context, key string, members ...interface{}) (int64, error)
+	SMembers(ctx context.Context, key string) ([]string, error)
+	SIsMember(ctx context.Context, key string, member interface{}) (bool, error)
+	SCard(ctx context.Context, key string) (int64, error)
+
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// In-memory database drivers
+const (
+	InMemoryDriverRedis  = "redis"
+	InMemoryDriverMemory = "memory"
+)
+
+// InMemoryDBConfig holds the configuration required to connect to an in-memory
+// database. Zero values use the client defaults.
+type InMemoryDBConfig struct {
+	// Driver is "redis" (default) or "memory", an in-process database
+	Driver string
+
+	// options of the memory driver (see NewMemoryDB)
+	MaxEntries      int
+	CleanupInterval time.Duration
+
+	Address  string
+	Password string
+	DB       int
+
+	// TLS enables TLS; TLSConfig may be used to customize it
+	TLS       bool
+	TLSConfig *tls.Config
+
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	PoolSize     int
+	MinIdleConns int
+	PoolTimeout  time.Duration
+	IdleTimeout  time.Duration
+	MaxRetries   int
 }
@@ -0,0 +1,95 @@
+package datasource
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+)
+
+// Instrumentations, registered by default unless noted
+const (
+	InstrumentationNone = "none"
+	InstrumentationLog  = "log" // logs the queries, see LogDriver
+
+	// InstrumentationNewRelic is registered by importing the nrdatasource
+	// package, so the New Relic agent is only linked when it's used
+	InstrumentationNewRelic = "newrelic"
+
+	// InstrumentationOpenTelemetry is registered by importing the
+	// oteldatasource module, which requires a newer Go version
+	InstrumentationOpenTelemetry = "otel"
+)
+
+// ErrInstrumentationNotSupported is returned when the instrumentation of the
+// configuration isn't registered
+var ErrInstrumentationNotSupported = errors.New("database instrumentation not supported")
+
+// Instrumentation returns the name of the database/sql driver that instruments
+// the driver driverName (mysql, postgres or sqlite3). The instrumented driver
+// must accept the same DSN.
+type Instrumentation func(driverName string) (string, error)
+
+var (
+	instrumentationsMutex sync.RWMutex
+	instrumentations      = map[string]Instrumentation{
+		InstrumentationNone: func(driverName string) (string, error) { return driverName, nil },
+		InstrumentationLog:  logDriver,
+	}
+)
+
+// RegisterInstrumentation makes an instrumentation available in DBConfig under
+// name, replacing any previous one (see nrdatasource and oteldatasource)
+func RegisterInstrumentation(name string, instrumentation Instrumentation) {
+	instrumentationsMutex.Lock()
+	defer instrumentationsMutex.Unlock()
+	instrumentations[name] = instrumentation
+}
+
+// returns the name of the database/sql driver to open for config
+func sqlDriverName(config DBConfig) (string, error) {
+	name := config.Instrumentation
+	if name == "" {
+		name = InstrumentationNone
+	}
+
+	instrumentationsMutex.RLock()
+	instrumentation, found := instrumentations[name]
+	instrumentationsMutex.RUnlock()
+	if !found {
+		return "", ErrInstrumentationNotSupported
+	}
+	return instrumentation(config.Driver)
+}
+
+func logDriver(driverName string) (string, error) {
+	return wrapDriver(driverName, InstrumentationLog, func(d driver.Driver) driver.Driver {
+		return &LogDriver{Driver: d, Name: driverName}
+	})
+}
+
+var (
+	wrappedDriversMutex sync.Mutex
+	wrappedDrivers      = make(map[string]bool)
+)
+
+// registers the driver driverName wrapped by wrap, once, as driverName+suffix
+func wrapDriver(driverName, suffix string, wrap func(driver.Driver) driver.Driver) (string, error) {
+	name := driverName + "+" + suffix
+
+	wrappedDriversMutex.Lock()
+	defer wrappedDriversMutex.Unlock()
+
+	if !wrappedDrivers[name] {
+		// opening doesn't connect, it's only used to get the driver
+		db, err := sql.Open(driverName, "")
+		if err != nil {
+			return "", ErrDriverNotSupported
+		}
+		defer db.Close()
+
+		sql.Register(name, wrap(db.Driver()))
+		wrappedDrivers[name] = true
+	}
+	return name, nil
+}
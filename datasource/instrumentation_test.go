@@ -0,0 +1,88 @@
+package datasource
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/logger"
+	"github.com/tuckyapps/lit-go-tools/logger/loggertest"
+)
+
+func TestInstrumentations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "instrumentation")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	for _, instrumentation := range []string{"", InstrumentationNone, InstrumentationLog} {
+		g := new(Generic)
+		db, err := g.New(DBConfig{
+			Driver:          "sqlite3",
+			DBName:          filepath.Join(dir, "instrumentation"+instrumentation+".db"),
+			Instrumentation: instrumentation,
+		})
+		if !assert.NoError(t, err, instrumentation) {
+			continue
+		}
+
+		_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+		assert.NoError(t, err, instrumentation)
+		_, err = db.Exec(db.Rebind("INSERT INTO users (name) VALUES (?)"), "ann")
+		assert.NoError(t, err, instrumentation)
+
+		var name string
+		assert.NoError(t, db.Get(&name, "SELECT name FROM users WHERE id = ?", 1), instrumentation)
+		assert.Equal(t, "ann", name, instrumentation)
+		assert.NoError(t, g.Close())
+	}
+}
+
+func TestLogInstrumentation(t *testing.T) {
+	rec, restore := loggertest.Install()
+	defer restore()
+
+	g := new(Generic)
+	db, err := g.New(DBConfig{Driver: "sqlite3", DBName: ":memory:", Instrumentation: InstrumentationLog})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer g.Close()
+
+	_, err = db.Exec("CREATE TABLE items (id INTEGER)")
+	assert.NoError(t, err)
+	rec.AssertLogged(t, logger.LevelDebug, "CREATE TABLE items")
+	rec.AssertLogged(t, logger.LevelDebug, "sqlite3")
+
+	stmt, err := db.Preparex("INSERT INTO items (id) VALUES (?)")
+	if assert.NoError(t, err) {
+		_, err = stmt.Exec(7)
+		assert.NoError(t, err)
+		stmt.Close()
+	}
+	rec.AssertLogged(t, logger.LevelDebug, "INSERT INTO items")
+
+	_, err = db.Exec("SELECT * FROM missing")
+	assert.Error(t, err)
+	rec.AssertLogged(t, logger.LevelWarn, "no such table")
+}
+
+func TestRegisterInstrumentation(t *testing.T) {
+	var instrumented []string
+	RegisterInstrumentation("test", func(driverName string) (string, error) {
+		instrumented = append(instrumented, driverName)
+		return driverName, nil
+	})
+
+	g := new(Generic)
+	_, err := g.New(DBConfig{Driver: "sqlite3", DBName: ":memory:", Instrumentation: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sqlite3"}, instrumented)
+	g.Close()
+
+	_, err = g.New(DBConfig{Driver: "sqlite3", DBName: ":memory:", Instrumentation: InstrumentationOpenTelemetry})
+	assert.Equal(t, ErrInstrumentationNotSupported, err)
+}
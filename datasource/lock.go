@@ -0,0 +1,373 @@
+package datasource
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Lock errors
+var (
+	ErrLockNotAcquired  = errors.New("lock held by another owner")
+	ErrLockNotHeld      = errors.New("lock not held")
+	ErrLockNotSupported = errors.New("locks not supported by the database driver")
+)
+
+// Default lock options
+const (
+	DefaultLockTTL           = 30 * time.Second
+	DefaultLockRetryInterval = 100 * time.Millisecond
+	defaultLockPrefix        = "lock:"
+)
+
+// Locker acquires named locks shared by all the processes using the same
+// database, e.g. so a job runs in only one replica.
+type Locker interface {
+	// TryLock acquires the lock if it's free, or returns ErrLockNotAcquired
+	TryLock(ctx context.Context, name string) (Lock, error)
+
+	// Lock waits until the lock is acquired, or ctx is done
+	Lock(ctx context.Context, name string) (Lock, error)
+}
+
+// Lock is an acquired lock. It's kept until Unlock is called, or until it's
+// lost because the database couldn't be reached.
+type Lock interface {
+	// Unlock releases the lock. It returns ErrLockNotHeld if it was lost.
+	Unlock(ctx context.Context) error
+
+	// Lost returns a channel that is closed if the lock is lost before
+	// calling Unlock, so the work it protects can be stopped.
+	Lost() <-chan struct{}
+}
+
+// LockerOptions holds the configuration of a Locker. Zero values use the defaults.
+type LockerOptions struct {
+	// TTL is the expiration of the locks in the in-memory database; they're
+	// renewed every TTL/3 while held, so it only applies when the owner dies.
+	TTL time.Duration
+
+	// RetryInterval is the wait between attempts in Lock
+	RetryInterval time.Duration
+
+	// Prefix is prepended to the names of the in-memory database locks
+	Prefix string
+}
+
+func (o *LockerOptions) setDefaults() {
+	if o.TTL <= 0 {
+		o.TTL = DefaultLockTTL
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = DefaultLockRetryInterval
+	}
+	if o.Prefix == "" {
+		o.Prefix = defaultLockPrefix
+	}
+}
+
+// waits until tryLock acquires the lock, retrying every interval
+func waitLock(ctx context.Context, interval time.Duration, tryLock func() (Lock, error)) (Lock, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		lock, err := tryLock()
+		if err != ErrLockNotAcquired {
+			return lock, err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// watchdog runs a check periodically while a lock is held; if it fails, the
+// lock is reported as lost
+type watchdog struct {
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+	lost     chan struct{}
+}
+
+// starts the watchdog; check receives the stop channel, closed by Unlock
+func startWatchdog(interval time.Duration, check func(stop <-chan struct{}) bool) *watchdog {
+	w := &watchdog{stop: make(chan struct{}), done: make(chan struct{}), lost: make(chan struct{})}
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if !check(w.stop) {
+					close(w.lost)
+					return
+				}
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+
+	return w
+}
+
+// stops the watchdog, returning false if the lock was lost
+func (w *watchdog) close() bool {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+
+	select {
+	case <-w.lost:
+		return false
+	default:
+		return true
+	}
+}
+
+// InMemoryLocker implements Locker with an InMemoryDB: locks are keys set if
+// they don't exist, with a random token as value, so only the owner can
+// release or renew them.
+type InMemoryLocker struct {
+	db      InMemoryDB
+	options LockerOptions
+}
+
+// NewInMemoryLocker creates a Locker that stores the locks in db
+func NewInMemoryLocker(db InMemoryDB, options LockerOptions) *InMemoryLocker {
+	options.setDefaults()
+	return &InMemoryLocker{db: db, options: options}
+}
+
+// TryLock acquires the lock if it's free, or returns ErrLockNotAcquired
+func (l *InMemoryLocker) TryLock(ctx context.Context, name string) (Lock, error) {
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key := l.options.Prefix + name
+	start := time.Now()
+	acquired, err := l.db.SetNX(ctx, key, token, l.options.TTL)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrLockNotAcquired
+	}
+
+	lock := &inMemoryLock{db: l.db, key: key, token: token, renewedAt: start}
+	lock.watchdog = startWatchdog(l.options.TTL/3, func(stop <-chan struct{}) bool {
+		return lock.renew(l.options.TTL, stop)
+	})
+	return lock, nil
+}
+
+// Lock waits until the lock is acquired, or ctx is done
+func (l *InMemoryLocker) Lock(ctx context.Context, name string) (Lock, error) {
+	return waitLock(ctx, l.options.RetryInterval, func() (Lock, error) {
+		return l.TryLock(ctx, name)
+	})
+}
+
+type inMemoryLock struct {
+	db       InMemoryDB
+	key      string
+	token    string
+	watchdog *watchdog
+
+	// time of the last request that set the expiration; only used by the
+	// watchdog once started
+	renewedAt time.Time
+}
+
+// extends the expiration, returning false if the lock was lost. Errors are
+// retried until shortly before the key expires, counting from the last
+// renewal, so the lock is reported as lost before another owner can take it.
+func (il *inMemoryLock) renew(ttl time.Duration, stop <-chan struct{}) bool {
+	deadline := il.renewedAt.Add(ttl - ttl/10)
+
+	for time.Now().Before(deadline) {
+		start := time.Now()
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		renewed, err := il.db.CompareAndExpire(ctx, il.key, il.token, ttl)
+		cancel()
+
+		if err == nil {
+			if renewed {
+				il.renewedAt = start
+			}
+			return renewed
+		}
+
+		wait := ttl / 10
+		if left := time.Until(deadline); left < wait {
+			wait = left
+		}
+		select {
+		case <-time.After(wait):
+		case <-stop:
+			return true
+		}
+	}
+	return false
+}
+
+func (il *inMemoryLock) Unlock(ctx context.Context) error {
+	if !il.watchdog.close() {
+		return ErrLockNotHeld
+	}
+
+	released, err := il.db.CompareAndDelete(ctx, il.key, il.token)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (il *inMemoryLock) Lost() <-chan struct{} {
+	return il.watchdog.lost
+}
+
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// queries of the advisory locks of each driver
+type advisoryLockQueries struct {
+	tryLock string // returns true if acquired
+	unlock  string
+	key     func(name string) interface{}
+}
+
+var advisoryLocks = map[string]advisoryLockQueries{
+	"mysql": {
+		tryLock: "SELECT COALESCE(GET_LOCK(?, 0), 0) = 1",
+		unlock:  "SELECT RELEASE_LOCK(?)",
+		key:     func(name string) interface{} { return name },
+	},
+	"postgres": {
+		tryLock: "SELECT pg_try_advisory_lock($1)",
+		unlock:  "SELECT pg_advisory_unlock($1)",
+		key:     advisoryLockKey,
+	},
+}
+
+// postgres advisory locks are identified by a number
+func advisoryLockKey(name string) interface{} {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// SQLLocker implements Locker with the advisory locks of the database (GET_LOCK
+// in MySQL, pg_advisory_lock in Postgres). Each lock holds a connection of the
+// pool until it's released, since the locks belong to the session. MySQL limits
+// the names to 64 characters.
+type SQLLocker struct {
+	db      *sqlx.DB
+	queries advisoryLockQueries
+	options LockerOptions
+}
+
+// NewSQLLocker creates a Locker using the advisory locks of db. It returns
+// ErrLockNotSupported if the driver doesn't support them.
+func NewSQLLocker(db *sqlx.DB, driverName string, options LockerOptions) (*SQLLocker, error) {
+	queries, supported := advisoryLocks[driverName]
+	if !supported {
+		return nil, ErrLockNotSupported
+	}
+
+	options.setDefaults()
+	return &SQLLocker{db: db, queries: queries, options: options}, nil
+}
+
+// TryLock acquires the lock if it's free, or returns ErrLockNotAcquired
+func (l *SQLLocker) TryLock(ctx context.Context, name string) (Lock, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := l.queries.key(name)
+	var acquired bool
+	if err = conn.QueryRowContext(ctx, l.queries.tryLock, key).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		if err == nil {
+			err = ErrLockNotAcquired
+		}
+		return nil, err
+	}
+
+	lock := &sqlLock{conn: conn, key: key, unlock: l.queries.unlock}
+	lock.watchdog = startWatchdog(l.options.TTL/3, func(<-chan struct{}) bool {
+		ctx, cancel := context.WithTimeout(context.Background(), l.options.TTL/3)
+		defer cancel()
+		return conn.PingContext(ctx) == nil
+	})
+	return lock, nil
+}
+
+// Lock waits until the lock is acquired, or ctx is done
+func (l *SQLLocker) Lock(ctx context.Context, name string) (Lock, error) {
+	return waitLock(ctx, l.options.RetryInterval, func() (Lock, error) {
+		return l.TryLock(ctx, name)
+	})
+}
+
+type sqlLock struct {
+	conn     *sql.Conn
+	key      interface{}
+	unlock   string
+	watchdog *watchdog
+}
+
+func (sl *sqlLock) Unlock(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			// the session may still hold the lock, so it's not returned to the pool
+			sl.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		sl.conn.Close()
+	}()
+
+	if !sl.watchdog.close() {
+		return ErrLockNotHeld
+	}
+
+	var released sql.NullBool
+	if err = sl.conn.QueryRowContext(ctx, sl.unlock, sl.key).Scan(&released); err != nil {
+		return err
+	}
+	if !released.Bool {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (sl *sqlLock) Lost() <-chan struct{} {
+	return sl.watchdog.lost
+}
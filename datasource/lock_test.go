@@ -0,0 +1,113 @@
+package datasource
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryLocker(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDB(0, 0)
+	defer db.Close()
+
+	locker := NewInMemoryLocker(db, LockerOptions{TTL: 60 * time.Millisecond, RetryInterval: 5 * time.Millisecond})
+
+	lock, err := locker.TryLock(ctx, "job")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = locker.TryLock(ctx, "job")
+	assert.Equal(t, ErrLockNotAcquired, err)
+
+	// renewed by the watchdog after the TTL
+	time.Sleep(150 * time.Millisecond)
+	_, err = locker.TryLock(ctx, "job")
+	assert.Equal(t, ErrLockNotAcquired, err)
+
+	acquired := make(chan Lock)
+	go func() {
+		l, err := locker.Lock(ctx, "job")
+		assert.NoError(t, err)
+		acquired <- l
+	}()
+
+	assert.NoError(t, lock.Unlock(ctx))
+	second := <-acquired
+	assert.Equal(t, ErrLockNotHeld, lock.Unlock(ctx))
+
+	// lost when another owner takes the key
+	db.Set(ctx, "lock:job", "other", 0)
+	select {
+	case <-second.Lost():
+	case <-time.After(time.Second):
+		t.Error("lock not reported as lost")
+	}
+	assert.Equal(t, ErrLockNotHeld, second.Unlock(ctx))
+}
+
+// failingDB fails to renew the locks while failing is set
+type failingDB struct {
+	*MemoryDB
+	failing int32
+}
+
+func (fd *failingDB) CompareAndExpire(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	if atomic.LoadInt32(&fd.failing) == 1 {
+		return false, ErrConnection
+	}
+	return fd.MemoryDB.CompareAndExpire(ctx, key, value, expiration)
+}
+
+func TestInMemoryLockLostBeforeExpiration(t *testing.T) {
+	ctx := context.Background()
+	db := &failingDB{MemoryDB: NewMemoryDB(0, 0)}
+	defer db.Close()
+
+	locker := NewInMemoryLocker(db, LockerOptions{TTL: 200 * time.Millisecond})
+	lock, err := locker.TryLock(ctx, "job")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// renewed at least once, then the store fails
+	time.Sleep(100 * time.Millisecond)
+	atomic.StoreInt32(&db.failing, 1)
+
+	select {
+	case <-lock.Lost():
+		// reported while the key still exists, so nobody else has the lock
+		n, _ := db.Exists(ctx, "lock:job")
+		assert.Equal(t, int64(1), n)
+	case <-time.After(time.Second):
+		t.Error("lock not reported as lost")
+	}
+}
+
+func TestLockCancelled(t *testing.T) {
+	db := NewMemoryDB(0, 0)
+	defer db.Close()
+
+	locker := NewInMemoryLocker(db, LockerOptions{})
+	lock, _ := locker.TryLock(context.Background(), "job")
+	defer lock.Unlock(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := locker.Lock(ctx, "job")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestSQLLockerNotSupported(t *testing.T) {
+	g := new(Generic)
+	_, err := g.New(DBConfig{Driver: "sqlite3", DBName: ":memory:"})
+	if assert.NoError(t, err) {
+		defer g.Close()
+		_, err = g.Locker(LockerOptions{})
+		assert.Equal(t, ErrLockNotSupported, err)
+	}
+}
@@ -0,0 +1,171 @@
+package datasource
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/tuckyapps/lit-go-tools/logger"
+)
+
+// LogDriver wraps a database/sql driver to log the statements at debug level,
+// with the time they took. Failed statements are logged at warning level. The
+// arguments are not logged, since they may hold personal data.
+type LogDriver struct {
+	driver.Driver
+	Name string // included in the 'driver' field
+}
+
+// Open returns a new connection to the database
+func (d *LogDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &logConn{Conn: conn, driver: d.Name}, nil
+}
+
+// logs the statement if err isn't driver.ErrSkip, which means the statement
+// wasn't run
+func logStatement(driverName, query string, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+
+	l := logger.GetLogger().WithFields(map[string]interface{}{
+		"driver":   driverName,
+		"duration": time.Since(start).String(),
+	})
+	if err != nil && err != driver.ErrBadConn {
+		l.With("error", err.Error()).Warn(query)
+	} else {
+		l.Debug(query)
+	}
+}
+
+// logConn implements the optional interfaces of database/sql/driver, using the
+// ones of the wrapped connection when available
+type logConn struct {
+	driver.Conn
+	driver string
+}
+
+func (c *logConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *logConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &logStmt{Stmt: stmt, driver: c.driver, query: query}, nil
+}
+
+func (c *logConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	start := time.Now()
+	defer func() { logStatement(c.driver, "BEGIN", start, err) }()
+
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *logConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	defer func() { logStatement(c.driver, query, start, err) }()
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *logConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	defer func() { logStatement(c.driver, query, start, err) }()
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *logConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *logConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *logConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	// database/sql uses the default conversion
+	return driver.ErrSkip
+}
+
+type logStmt struct {
+	driver.Stmt
+	driver string
+	query  string
+}
+
+func (s *logStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *logStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (result driver.Result, err error) {
+	start := time.Now()
+	defer func() { logStatement(s.driver, s.query, start, err) }()
+
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+	return s.Stmt.Exec(values(args))
+}
+
+func (s *logStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *logStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	start := time.Now()
+	defer func() { logStatement(s.driver, s.query, start, err) }()
+
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+	return s.Stmt.Query(values(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	v := make([]driver.Value, len(args))
+	for i, arg := range args {
+		v[i] = arg.Value
+	}
+	return v
+}
@@ -0,0 +1,939 @@
+package datasource
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// default interval between the removals of expired keys
+const defaultCleanupInterval = time.Minute
+
+var errMemoryDBClosed = fmt.Errorf("%w: in-memory database is closed", ErrConnection)
+
+// MemoryDB is an in-process implementation of InMemoryDB, useful for tests and
+// single-node tools that shouldn't depend on a Redis server. Expired keys are
+// removed when accessed and periodically by a background goroutine, until
+// Close is called. If MaxEntries is set, the least recently used keys are
+// evicted to make room for new ones. It's safe for concurrent use.
+type MemoryDB struct {
+	mutex      sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List // front is the most recently used
+	maxEntries int
+	closed     bool
+	stop       chan struct{}
+	now        func() time.Time
+}
+
+// memoryEntry is a key of MemoryDB. Its value is a []byte for strings, and a
+// map[string][]byte, *list.List or map[string]struct{} for hashes, lists and
+// sets.
+type memoryEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// NewMemoryDB creates an in-process database. maxEntries limits the number of
+// keys (0 means no limit), and cleanupInterval is the time between the
+// removals of expired keys (1 minute if 0).
+func NewMemoryDB(maxEntries int, cleanupInterval time.Duration) *MemoryDB {
+	if cleanupInterval <= 0 {
+		cleanupInterval = defaultCleanupInterval
+	}
+
+	m := &MemoryDB{
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+		stop:       make(chan struct{}),
+		now:        time.Now,
+	}
+	go m.janitor(cleanupInterval)
+
+	return m
+}
+
+// removes the expired keys periodically
+func (m *MemoryDB) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.removeExpired()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *MemoryDB) removeExpired() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.now()
+	for _, elem := range m.entries {
+		if entry := elem.Value.(*memoryEntry); entry.expired(now) {
+			m.remove(elem)
+		}
+	}
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// locks the database, checking that it's open and ctx is not done. The caller
+// must unlock it if no error is returned.
+func (m *MemoryDB) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+		return errMemoryDBClosed
+	}
+	return nil
+}
+
+// returns the entry of the key, or nil if it doesn't exist or has expired;
+// mutex must be held
+func (m *MemoryDB) lookup(key string) *memoryEntry {
+	elem, exists := m.entries[key]
+	if !exists {
+		return nil
+	}
+
+	entry := elem.Value.(*memoryEntry)
+	if entry.expired(m.now()) {
+		m.remove(elem)
+		return nil
+	}
+
+	m.lru.MoveToFront(elem)
+	return entry
+}
+
+// returns the value of the key if it's of the same type as zero, which is
+// created if the key doesn't exist and create is true; mutex must be held
+func (m *MemoryDB) lookupAs(key string, create bool, zero func() interface{}, is func(interface{}) bool) (*memoryEntry, error) {
+	entry := m.lookup(key)
+	if entry == nil {
+		if !create {
+			return nil, nil
+		}
+		return m.store(key, zero(), time.Time{}), nil
+	}
+
+	if !is(entry.value) {
+		return nil, ErrTypeMismatch
+	}
+	return entry, nil
+}
+
+// sets the value of the key, evicting the least recently used keys if the
+// limit is reached; mutex must be held
+func (m *MemoryDB) store(key string, value interface{}, expiresAt time.Time) *memoryEntry {
+	if elem, exists := m.entries[key]; exists {
+		entry := elem.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		m.lru.MoveToFront(elem)
+		return entry
+	}
+
+	for m.maxEntries > 0 && len(m.entries) >= m.maxEntries {
+		m.remove(m.lru.Back())
+	}
+
+	entry := &memoryEntry{key: key, value: value, expiresAt: expiresAt}
+	m.entries[key] = m.lru.PushFront(entry)
+	return entry
+}
+
+// mutex must be held
+func (m *MemoryDB) remove(elem *list.Element) {
+	m.lru.Remove(elem)
+	delete(m.entries, elem.Value.(*memoryEntry).key)
+}
+
+// removes the key if its collection is empty, like Redis does; mutex must be held
+func (m *MemoryDB) removeIfEmpty(entry *memoryEntry, size int) {
+	if size == 0 {
+		m.remove(m.entries[entry.key])
+	}
+}
+
+func (m *MemoryDB) expiration(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return m.now().Add(expiration)
+}
+
+// Len returns the number of keys, including the expired ones not removed yet
+func (m *MemoryDB) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.entries)
+}
+
+// Ping returns an error if the database is closed
+func (m *MemoryDB) Ping(ctx context.Context) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	m.mutex.Unlock()
+	return nil
+}
+
+// Close stops the background cleanup and discards the keys. Operations fail
+// with ErrConnection after closing it.
+func (m *MemoryDB) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.closed {
+		m.closed = true
+		close(m.stop)
+		m.entries = make(map[string]*list.Element)
+		m.lru.Init()
+	}
+	return nil
+}
+
+// Get returns a value associated with the provided key, or ErrKeyNotFound.
+func (m *MemoryDB) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mutex.Unlock()
+
+	entry := m.lookup(key)
+	if entry == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	b, ok := entry.value.([]byte)
+	if !ok {
+		return nil, ErrTypeMismatch
+	}
+	return copyBytes(b), nil
+}
+
+// Set sets a value for the provided key. Zero expiration means the key doesn't
+// expire.
+func (m *MemoryDB) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	b, err := toBytes(value)
+	if err != nil {
+		return err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mutex.Unlock()
+
+	m.store(key, b, m.expiration(expiration))
+	return nil
+}
+
+// SetNX sets a value for the provided key only if it doesn't exist. It returns
+// true if the value was set.
+func (m *MemoryDB) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	b, err := toBytes(value)
+	if err != nil {
+		return false, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mutex.Unlock()
+
+	if m.lookup(key) != nil {
+		return false, nil
+	}
+	m.store(key, b, m.expiration(expiration))
+	return true, nil
+}
+
+// Delete removes the keys, returning the number of keys removed.
+func (m *MemoryDB) Delete(ctx context.Context, keys ...string) (int64, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mutex.Unlock()
+
+	var removed int64
+	for _, key := range keys {
+		if m.lookup(key) != nil {
+			m.remove(m.entries[key])
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Exists returns how many of the keys exist.
+func (m *MemoryDB) Exists(ctx context.Context, keys ...string) (int64, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mutex.Unlock()
+
+	var count int64
+	for _, key := range keys {
+		if m.lookup(key) != nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Expire sets the expiration of the key; non positive values remove it. It
+// returns false if the key doesn't exist.
+func (m *MemoryDB) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mutex.Unlock()
+
+	entry := m.lookup(key)
+	if entry == nil {
+		return false, nil
+	}
+
+	if expiration <= 0 {
+		m.remove(m.entries[key])
+	} else {
+		entry.expiresAt = m.now().Add(expiration)
+	}
+	return true, nil
+}
+
+// TTL returns the remaining time to live of the key; -1s if the key has no
+// expiration, and -2s if it doesn't exist.
+func (m *MemoryDB) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mutex.Unlock()
+
+	entry := m.lookup(key)
+	switch {
+	case entry == nil:
+		return -2 * time.Second, nil
+	case entry.expiresAt.IsZero():
+		return -1 * time.Second, nil
+	default:
+		return entry.expiresAt.Sub(m.now()).Round(time.Second), nil
+	}
+}
+
+// Incr increments the integer value of the key by one, returning the new value.
+func (m *MemoryDB) Incr(ctx context.Context, key string) (int64, error) {
+	return m.IncrBy(ctx, key, 1)
+}
+
+// IncrBy increments the integer value of the key by value. Missing keys are
+// considered 0.
+func (m *MemoryDB) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mutex.Unlock()
+
+	var current int64
+	var expiresAt time.Time
+
+	if entry := m.lookup(key); entry != nil {
+		b, ok := entry.value.([]byte)
+		if !ok {
+			return 0, ErrTypeMismatch
+		}
+
+		var err error
+		if current, err = strconv.ParseInt(string(b), 10, 64); err != nil {
+			return 0, ErrTypeMismatch
+		}
+		expiresAt = entry.expiresAt
+	}
+
+	current += value
+	m.store(key, []byte(strconv.FormatInt(current, 10)), expiresAt)
+	return current, nil
+}
+
+// Decr decrements the integer value of the key by one, returning the new value.
+func (m *MemoryDB) Decr(ctx context.Context, key string) (int64, error) {
+	return m.IncrBy(ctx, key, -1)
+}
+
+// DecrBy decrements the integer value of the key by value.
+func (m *MemoryDB) DecrBy(ctx context.Context, key string, value int64) (int64, error) {
+	return m.IncrBy(ctx, key, -value)
+}
+
+// MGet returns the values of the keys, in the same order; missing keys have a
+// nil value.
+func (m *MemoryDB) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mutex.Unlock()
+
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		if entry := m.lookup(key); entry != nil {
+			if b, ok := entry.value.([]byte); ok {
+				values[i] = copyBytes(b)
+			}
+		}
+	}
+	return values, nil
+}
+
+// MSet sets the values of several keys at once.
+func (m *MemoryDB) MSet(ctx context.Context, values map[string]interface{}) error {
+	converted := make(map[string][]byte, len(values))
+	for k, v := range values {
+		b, err := toBytes(v)
+		if err != nil {
+			return err
+		}
+		converted[k] = b
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mutex.Unlock()
+
+	for k, b := range converted {
+		m.store(k, b, time.Time{})
+	}
+	return nil
+}
+
+// CompareAndDelete removes the key only if its value is value. It returns true
+// if the key was removed.
+func (m *MemoryDB) CompareAndDelete(ctx context.Context, key string, value interface{}) (bool, error) {
+	b, err := toBytes(value)
+	if err != nil {
+		return false, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mutex.Unlock()
+
+	if !m.hasValue(key, b) {
+		return false, nil
+	}
+	m.remove(m.entries[key])
+	return true, nil
+}
+
+// CompareAndExpire sets the expiration of the key only if its value is value.
+// It returns true if the expiration was set.
+func (m *MemoryDB) CompareAndExpire(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	b, err := toBytes(value)
+	if err != nil {
+		return false, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mutex.Unlock()
+
+	if !m.hasValue(key, b) {
+		return false, nil
+	}
+
+	if expiration <= 0 {
+		m.remove(m.entries[key])
+	} else {
+		m.entries[key].Value.(*memoryEntry).expiresAt = m.now().Add(expiration)
+	}
+	return true, nil
+}
+
+// returns true if the key is a string with the value; mutex must be held
+func (m *MemoryDB) hasValue(key string, value []byte) bool {
+	entry := m.lookup(key)
+	if entry == nil {
+		return false
+	}
+
+	current, ok := entry.value.([]byte)
+	return ok && bytes.Equal(current, value)
+}
+
+func newHash() interface{} { return make(map[string][]byte) }
+
+func isHash(v interface{}) bool {
+	_, ok := v.(map[string][]byte)
+	return ok
+}
+
+// HSet sets the field of the hash stored at key.
+func (m *MemoryDB) HSet(ctx context.Context, key, field string, value interface{}) error {
+	return m.HMSet(ctx, key, map[string]interface{}{field: value})
+}
+
+// HMSet sets several fields of the hash stored at key.
+func (m *MemoryDB) HMSet(ctx context.Context, key string, fields map[string]interface{}) error {
+	converted := make(map[string][]byte, len(fields))
+	for k, v := range fields {
+		b, err := toBytes(v)
+		if err != nil {
+			return err
+		}
+		converted[k] = b
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mutex.Unlock()
+
+	// nothing to set, the key isn't created (as in Redis)
+	entry, err := m.lookupAs(key, len(converted) > 0, newHash, isHash)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	hash := entry.value.(map[string][]byte)
+	for k, b := range converted {
+		hash[k] = b
+	}
+	return nil
+}
+
+// HGet returns the value of the field of the hash stored at key, or
+// ErrKeyNotFound if the key or the field don't exist.
+func (m *MemoryDB) HGet(ctx context.Context, key, field string) ([]byte, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newHash, isHash)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	b, exists := entry.value.(map[string][]byte)[field]
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+	return copyBytes(b), nil
+}
+
+// HGetAll returns all the fields of the hash stored at key.
+func (m *MemoryDB) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newHash, isHash)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string)
+	if entry != nil {
+		for k, b := range entry.value.(map[string][]byte) {
+			fields[k] = string(b)
+		}
+	}
+	return fields, nil
+}
+
+// HExists returns true if the field exists in the hash stored at key.
+func (m *MemoryDB) HExists(ctx context.Context, key, field string) (bool, error) {
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newHash, isHash)
+	if err != nil || entry == nil {
+		return false, err
+	}
+
+	_, exists := entry.value.(map[string][]byte)[field]
+	return exists, nil
+}
+
+// HDel removes the fields from the hash stored at key, returning the number of
+// fields removed.
+func (m *MemoryDB) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newHash, isHash)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+
+	hash := entry.value.(map[string][]byte)
+	var removed int64
+	for _, field := range fields {
+		if _, exists := hash[field]; exists {
+			delete(hash, field)
+			removed++
+		}
+	}
+
+	m.removeIfEmpty(entry, len(hash))
+	return removed, nil
+}
+
+func newList() interface{} { return list.New() }
+
+func isList(v interface{}) bool {
+	_, ok := v.(*list.List)
+	return ok
+}
+
+// LPush inserts the values at the head of the list, returning its length.
+func (m *MemoryDB) LPush(ctx context.Context, key string, values ...interface{}) (int64, error) {
+	return m.push(ctx, key, values, true)
+}
+
+// RPush inserts the values at the tail of the list, returning its length.
+func (m *MemoryDB) RPush(ctx context.Context, key string, values ...interface{}) (int64, error) {
+	return m.push(ctx, key, values, false)
+}
+
+func (m *MemoryDB) push(ctx context.Context, key string, values []interface{}, head bool) (int64, error) {
+	converted := make([][]byte, len(values))
+	for i, v := range values {
+		b, err := toBytes(v)
+		if err != nil {
+			return 0, err
+		}
+		converted[i] = b
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mutex.Unlock()
+
+	// nothing to push, the key isn't created (as in Redis)
+	entry, err := m.lookupAs(key, len(converted) > 0, newList, isList)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+
+	l := entry.value.(*list.List)
+	for _, b := range converted {
+		if head {
+			l.PushFront(b)
+		} else {
+			l.PushBack(b)
+		}
+	}
+	return int64(l.Len()), nil
+}
+
+// LPop removes and returns the first element of the list, or ErrKeyNotFound
+// if the list is empty.
+func (m *MemoryDB) LPop(ctx context.Context, key string) ([]byte, error) {
+	return m.pop(ctx, key, true)
+}
+
+// RPop removes and returns the last element of the list, or ErrKeyNotFound if
+// the list is empty.
+func (m *MemoryDB) RPop(ctx context.Context, key string) ([]byte, error) {
+	return m.pop(ctx, key, false)
+}
+
+func (m *MemoryDB) pop(ctx context.Context, key string, head bool) ([]byte, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newList, isList)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	l := entry.value.(*list.List)
+	elem := l.Back()
+	if head {
+		elem = l.Front()
+	}
+	l.Remove(elem)
+
+	m.removeIfEmpty(entry, l.Len())
+	return elem.Value.([]byte), nil
+}
+
+// LRange returns the elements of the list between start and stop, inclusive.
+// Negative indexes are offsets from the end of the list.
+func (m *MemoryDB) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newList, isList)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []string{}
+	if entry == nil {
+		return values, nil
+	}
+
+	l := entry.value.(*list.List)
+	length := int64(l.Len())
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+
+	var i int64
+	for elem := l.Front(); elem != nil && i <= stop; elem, i = elem.Next(), i+1 {
+		if i >= start {
+			values = append(values, string(elem.Value.([]byte)))
+		}
+	}
+	return values, nil
+}
+
+// LLen returns the length of the list.
+func (m *MemoryDB) LLen(ctx context.Context, key string) (int64, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newList, isList)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+	return int64(entry.value.(*list.List).Len()), nil
+}
+
+func newSet() interface{} { return make(map[string]struct{}) }
+
+func isSet(v interface{}) bool {
+	_, ok := v.(map[string]struct{})
+	return ok
+}
+
+// SAdd adds the members to the set, returning the number of members added.
+func (m *MemoryDB) SAdd(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	converted, err := toStrings(members)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mutex.Unlock()
+
+	// nothing to add, the key isn't created (as in Redis)
+	entry, err := m.lookupAs(key, len(converted) > 0, newSet, isSet)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+
+	set := entry.value.(map[string]struct{})
+	var added int64
+	for _, member := range converted {
+		if _, exists := set[member]; !exists {
+			set[member] = struct{}{}
+			added++
+		}
+	}
+	return added, nil
+}
+
+// SRem removes the members from the set, returning the number of members removed.
+func (m *MemoryDB) SRem(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	converted, err := toStrings(members)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newSet, isSet)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+
+	set := entry.value.(map[string]struct{})
+	var removed int64
+	for _, member := range converted {
+		if _, exists := set[member]; exists {
+			delete(set, member)
+			removed++
+		}
+	}
+
+	m.removeIfEmpty(entry, len(set))
+	return removed, nil
+}
+
+// SMembers returns all the members of the set, sorted.
+func (m *MemoryDB) SMembers(ctx context.Context, key string) ([]string, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newSet, isSet)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []string{}
+	if entry != nil {
+		for member := range entry.value.(map[string]struct{}) {
+			members = append(members, member)
+		}
+		sort.Strings(members)
+	}
+	return members, nil
+}
+
+// SIsMember returns true if member belongs to the set.
+func (m *MemoryDB) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
+	b, err := toBytes(member)
+	if err != nil {
+		return false, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newSet, isSet)
+	if err != nil || entry == nil {
+		return false, err
+	}
+
+	_, exists := entry.value.(map[string]struct{})[string(b)]
+	return exists, nil
+}
+
+// SCard returns the number of members of the set.
+func (m *MemoryDB) SCard(ctx context.Context, key string) (int64, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mutex.Unlock()
+
+	entry, err := m.lookupAs(key, false, newSet, isSet)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+	return int64(len(entry.value.(map[string]struct{}))), nil
+}
+
+// converts the value the same way the Redis client does
+func toBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return copyBytes(v), nil
+	case int:
+		return []byte(strconv.FormatInt(int64(v), 10)), nil
+	case int8:
+		return []byte(strconv.FormatInt(int64(v), 10)), nil
+	case int16:
+		return []byte(strconv.FormatInt(int64(v), 10)), nil
+	case int32:
+		return []byte(strconv.FormatInt(int64(v), 10)), nil
+	case int64:
+		return []byte(strconv.FormatInt(v, 10)), nil
+	case uint:
+		return []byte(strconv.FormatUint(uint64(v), 10)), nil
+	case uint8:
+		return []byte(strconv.FormatUint(uint64(v), 10)), nil
+	case uint16:
+		return []byte(strconv.FormatUint(uint64(v), 10)), nil
+	case uint32:
+		return []byte(strconv.FormatUint(uint64(v), 10)), nil
+	case uint64:
+		return []byte(strconv.FormatUint(v, 10)), nil
+	case float32:
+		return []byte(strconv.FormatFloat(float64(v), 'f', -1, 32)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case bool:
+		if v {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case time.Time:
+		return []byte(v.Format(time.RFC3339Nano)), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	default:
+		return nil, fmt.Errorf("can't marshal %T (implement encoding.BinaryMarshaler)", value)
+	}
+}
+
+func toStrings(values []interface{}) ([]string, error) {
+	converted := make([]string, len(values))
+	for i, v := range values {
+		b, err := toBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = string(b)
+	}
+	return converted, nil
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte{}, b...)
+}
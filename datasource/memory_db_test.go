@@ -0,0 +1,162 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryDBStrings(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryDB(0, 0)
+	defer m.Close()
+
+	_, err := m.Get(ctx, "missing")
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+
+	assert.NoError(t, m.Set(ctx, "a", "1", 0))
+	value, err := m.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", string(value))
+
+	set, _ := m.SetNX(ctx, "a", "2", 0)
+	assert.False(t, set)
+
+	n, err := m.IncrBy(ctx, "a", 9)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), n)
+	n, _ = m.Decr(ctx, "counter")
+	assert.Equal(t, int64(-1), n)
+
+	assert.NoError(t, m.MSet(ctx, map[string]interface{}{"b": 2.5, "c": true}))
+	values, _ := m.MGet(ctx, "b", "missing", "c")
+	assert.Equal(t, [][]byte{[]byte("2.5"), nil, []byte("1")}, values)
+
+	n, _ = m.Delete(ctx, "a", "b", "missing")
+	assert.Equal(t, int64(2), n)
+	n, _ = m.Exists(ctx, "a", "c")
+	assert.Equal(t, int64(1), n)
+}
+
+func TestMemoryDBExpiration(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryDB(0, 10*time.Millisecond)
+	defer m.Close()
+
+	now := time.Now()
+	m.mutex.Lock()
+	m.now = func() time.Time { return now }
+	m.mutex.Unlock()
+
+	m.Set(ctx, "a", "1", time.Minute)
+	m.Set(ctx, "b", "1", 0)
+
+	ttl, _ := m.TTL(ctx, "a")
+	assert.Equal(t, time.Minute, ttl)
+	ttl, _ = m.TTL(ctx, "b")
+	assert.Equal(t, -time.Second, ttl)
+	ttl, _ = m.TTL(ctx, "missing")
+	assert.Equal(t, -2*time.Second, ttl)
+
+	m.mutex.Lock()
+	m.now = func() time.Time { return now.Add(time.Hour) }
+	m.mutex.Unlock()
+
+	// removed by the janitor
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, m.Len())
+	_, err := m.Get(ctx, "a")
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestMemoryDBEviction(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryDB(2, 0)
+	defer m.Close()
+
+	m.Set(ctx, "a", "1", 0)
+	m.Set(ctx, "b", "2", 0)
+	m.Get(ctx, "a") // b is now the least recently used
+	m.Set(ctx, "c", "3", 0)
+
+	n, _ := m.Exists(ctx, "a", "b", "c")
+	assert.Equal(t, int64(2), n)
+	_, err := m.Get(ctx, "b")
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestMemoryDBCollections(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryDB(0, 0)
+	defer m.Close()
+
+	assert.NoError(t, m.HMSet(ctx, "h", map[string]interface{}{"a": 1, "b": "x"}))
+	value, _ := m.HGet(ctx, "h", "a")
+	assert.Equal(t, "1", string(value))
+	_, err := m.HGet(ctx, "h", "missing")
+	assert.Equal(t, ErrKeyNotFound, err)
+	all, _ := m.HGetAll(ctx, "h")
+	assert.Equal(t, map[string]string{"a": "1", "b": "x"}, all)
+	m.HDel(ctx, "h", "a", "b")
+	n, _ := m.Exists(ctx, "h")
+	assert.Equal(t, int64(0), n)
+
+	m.RPush(ctx, "l", "b", "c")
+	m.LPush(ctx, "l", "a")
+	items, _ := m.LRange(ctx, "l", 0, -1)
+	assert.Equal(t, []string{"a", "b", "c"}, items)
+	items, _ = m.LRange(ctx, "l", -2, 10)
+	assert.Equal(t, []string{"b", "c"}, items)
+	last, _ := m.RPop(ctx, "l")
+	assert.Equal(t, "c", string(last))
+	n, _ = m.LLen(ctx, "l")
+	assert.Equal(t, int64(2), n)
+
+	n, _ = m.SAdd(ctx, "s", "x", "y", "x")
+	assert.Equal(t, int64(2), n)
+	members, _ := m.SMembers(ctx, "s")
+	assert.Equal(t, []string{"x", "y"}, members)
+	isMember, _ := m.SIsMember(ctx, "s", "y")
+	assert.True(t, isMember)
+
+	_, err = m.Get(ctx, "s")
+	assert.True(t, errors.Is(err, ErrTypeMismatch))
+	_, err = m.LPush(ctx, "s", "z")
+	assert.True(t, errors.Is(err, ErrTypeMismatch))
+
+	// empty collections are never created
+	n, err = m.RPush(ctx, "empty")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+	n, _ = m.LPush(ctx, "l")
+	assert.Equal(t, int64(2), n)
+	m.SAdd(ctx, "empty")
+	assert.NoError(t, m.HMSet(ctx, "empty", nil))
+	n, _ = m.Exists(ctx, "empty")
+	assert.Equal(t, int64(0), n)
+}
+
+func TestMemoryDBClosedAndCancelled(t *testing.T) {
+	m := NewMemoryDB(0, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, m.Set(ctx, "a", "1", 0))
+
+	assert.NoError(t, m.Close())
+	assert.True(t, errors.Is(m.Ping(context.Background()), ErrConnection))
+}
+
+func TestNewInMemoryDB(t *testing.T) {
+	db, err := NewInMemoryDB(InMemoryDBConfig{Driver: InMemoryDriverMemory})
+	if assert.NoError(t, err) {
+		assert.IsType(t, &MemoryDB{}, db)
+		db.Close()
+	}
+
+	_, err = NewInMemoryDB(InMemoryDBConfig{Driver: "memcached"})
+	assert.Equal(t, ErrDriverNotSupported, err)
+}
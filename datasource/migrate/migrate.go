@@ -0,0 +1,326 @@
+// Package migrate applies versioned schema migrations to the databases of the
+// datasource package (mysql, postgres and sqlite3). Migrations are SQL files,
+// read with ReadDir or ReadFileSystem, or Go functions. The applied versions are
+// recorded in a table, schema_migrations by default.
+//
+//	migrations, err := migrate.ReadDir("migrations")
+//	...
+//	m, err := migrate.New(db, migrations, migrate.Options{})
+//	...
+//	applied, err := m.Up(ctx, 0)
+//
+// Each migration runs in a transaction. MySQL commits DDL statements
+// implicitly, so a failed MySQL migration may be partially applied.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tuckyapps/lit-go-tools/datasource"
+	"github.com/tuckyapps/lit-go-tools/logger"
+)
+
+// Migration errors
+var (
+	ErrDuplicateVersion = errors.New("migrate: duplicate migration version")
+	ErrNoUp             = errors.New("migrate: migration without up")
+	ErrNoDown           = errors.New("migrate: migration can't be rolled back")
+	ErrMissingMigration = errors.New("migrate: applied migration not found")
+	ErrInvalidTable     = errors.New("migrate: invalid table name")
+)
+
+const defaultTable = "schema_migrations"
+
+var tableName = regexp.MustCompile(`^\w+$`)
+
+// Migration is a schema change. Its up and down steps are SQL statements or Go
+// functions; the functions are used when the SQL is empty.
+type Migration struct {
+	Version int64
+	Name    string
+
+	UpSQL   string
+	DownSQL string
+
+	Up   func(ctx context.Context, tx *sqlx.Tx) error
+	Down func(ctx context.Context, tx *sqlx.Tx) error
+}
+
+// String returns the version and the name of the migration, e.g. 1_create_users
+func (m Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// Status is the state of a migration
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+
+	// Missing is true if the migration was applied, but it's not known by the
+	// Migrator, e.g. because it was applied by a newer version of the service
+	Missing bool
+}
+
+// Options holds the configuration of a Migrator
+type Options struct {
+	// Table records the applied migrations, schema_migrations by default. Its
+	// name is also used for the lock.
+	Table string
+
+	// DryRun makes Up, UpTo and Down return the migrations they would run,
+	// without running them
+	DryRun bool
+}
+
+// Migrator applies and rolls back migrations. When the database supports
+// advisory locks (see datasource.Generic.CanLock), they are taken, so only one
+// process migrates at a time.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+	options    Options
+	locker     datasource.Locker
+}
+
+// New creates a Migrator of the migrations, which can be in any order
+func New(db *sqlx.DB, migrations []Migration, options Options) (*Migrator, error) {
+	if options.Table == "" {
+		options.Table = defaultTable
+	}
+	if !tableName.MatchString(options.Table) {
+		return nil, ErrInvalidTable
+	}
+
+	sorted := append([]Migration(nil), migrations...)
+	sortMigrations(sorted)
+	for i, m := range sorted {
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, m.Version)
+		}
+		if m.UpSQL == "" && m.Up == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNoUp, m)
+		}
+	}
+
+	m := &Migrator{db: db, migrations: sorted, options: options}
+	locker, err := datasource.NewSQLLocker(db, db.DriverName(), datasource.LockerOptions{})
+	switch err {
+	case nil:
+		m.locker = locker
+	case datasource.ErrLockNotSupported:
+		// sqlite3 only allows one writer, there's no need to lock
+	default:
+		return nil, err
+	}
+	return m, nil
+}
+
+// Status returns the state of all the migrations, sorted by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []Status
+	for _, migration := range m.migrations {
+		s := Status{Version: migration.Version, Name: migration.Name}
+		if a, found := applied[migration.Version]; found {
+			s.Applied = true
+			s.AppliedAt = a.AppliedAt
+			delete(applied, migration.Version)
+		}
+		status = append(status, s)
+	}
+	for _, a := range applied {
+		a.Missing = true
+		status = append(status, a)
+	}
+
+	sortStatus(status)
+	return status, nil
+}
+
+// Up applies the pending migrations, up to steps of them if steps is positive.
+// It returns the migrations applied.
+func (m *Migrator) Up(ctx context.Context, steps int) ([]Migration, error) {
+	return m.up(ctx, steps, -1)
+}
+
+// UpTo applies the pending migrations with a version lower or equal to version
+func (m *Migrator) UpTo(ctx context.Context, version int64) ([]Migration, error) {
+	return m.up(ctx, 0, version)
+}
+
+func (m *Migrator) up(ctx context.Context, steps int, maxVersion int64) ([]Migration, error) {
+	return m.run(ctx, func(applied map[int64]Status) ([]Migration, error) {
+		var pending []Migration
+		for _, migration := range m.migrations {
+			if maxVersion >= 0 && migration.Version > maxVersion {
+				break
+			}
+			if _, found := applied[migration.Version]; !found {
+				pending = append(pending, migration)
+			}
+		}
+		if steps > 0 && len(pending) > steps {
+			pending = pending[:steps]
+		}
+
+		return m.apply(ctx, pending, true)
+	})
+}
+
+// Down rolls back the last steps migrations applied, or all of them if steps
+// isn't positive. It returns the migrations rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	return m.run(ctx, func(applied map[int64]Status) ([]Migration, error) {
+		var rollback []Migration
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, found := applied[m.migrations[i].Version]; found {
+				rollback = append(rollback, m.migrations[i])
+				delete(applied, m.migrations[i].Version)
+			}
+		}
+
+		if steps > 0 && len(rollback) > steps {
+			rollback = rollback[:steps]
+		}
+
+		// unknown versions would be skipped
+		for version := range applied {
+			if len(rollback) == 0 || version > rollback[len(rollback)-1].Version {
+				return nil, fmt.Errorf("%w: %d", ErrMissingMigration, version)
+			}
+		}
+		return m.apply(ctx, rollback, false)
+	})
+}
+
+// takes the lock and creates the table, unless it's a dry run, and calls fn
+// with the applied migrations
+func (m *Migrator) run(ctx context.Context, fn func(applied map[int64]Status) ([]Migration, error)) ([]Migration, error) {
+	if !m.options.DryRun {
+		if m.locker != nil {
+			lock, err := m.locker.Lock(ctx, m.options.Table)
+			if err != nil {
+				return nil, err
+			}
+			defer lock.Unlock(context.Background())
+		}
+
+		if _, err := m.db.ExecContext(ctx, createTableQuery(m.options.Table)); err != nil {
+			return nil, err
+		}
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fn(applied)
+}
+
+// runs the up or down step of the migrations, in order, stopping at the first
+// error. It returns the migrations run.
+func (m *Migrator) apply(ctx context.Context, migrations []Migration, up bool) ([]Migration, error) {
+	if m.options.DryRun {
+		for _, migration := range migrations {
+			if !up && migration.DownSQL == "" && migration.Down == nil {
+				return nil, fmt.Errorf("%w: %s", ErrNoDown, migration)
+			}
+		}
+		return migrations, nil
+	}
+
+	var done []Migration
+	for _, migration := range migrations {
+		if err := m.applyOne(ctx, migration, up); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+
+		if up {
+			logger.GetLogger().Infof("migrate: applied %s", migration)
+		} else {
+			logger.GetLogger().Infof("migrate: rolled back %s", migration)
+		}
+	}
+	return done, nil
+}
+
+func (m *Migrator) applyOne(ctx context.Context, migration Migration, up bool) (err error) {
+	query, fn := migration.UpSQL, migration.Up
+	if !up {
+		query, fn = migration.DownSQL, migration.Down
+		if query == "" && fn == nil {
+			return fmt.Errorf("%w: %s", ErrNoDown, migration)
+		}
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("migrate: %s: %w", migration, err)
+		}
+	}()
+
+	if query != "" {
+		for _, statement := range statements(m.db.DriverName(), query) {
+			if _, err = tx.ExecContext(ctx, statement); err != nil {
+				return
+			}
+		}
+	} else if err = fn(ctx, tx); err != nil {
+		return
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf(
+			"INSERT INTO %s (version, name, applied_at) VALUES (?, ?, ?)", m.options.Table)),
+			migration.Version, migration.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf(
+			"DELETE FROM %s WHERE version = ?", m.options.Table)), migration.Version)
+	}
+	if err != nil {
+		return
+	}
+
+	return tx.Commit()
+}
+
+// returns the applied migrations by version; none if the table doesn't exist
+func (m *Migrator) applied(ctx context.Context) (map[int64]Status, error) {
+	exists, err := tableExists(ctx, m.db, m.options.Table)
+	if err != nil || !exists {
+		return map[int64]Status{}, err
+	}
+
+	var rows []struct {
+		Version   int64     `db:"version"`
+		Name      string    `db:"name"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	query := fmt.Sprintf("SELECT version, name, applied_at FROM %s", m.options.Table)
+	if err = m.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]Status, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = Status{Version: r.Version, Name: r.Name, Applied: true, AppliedAt: r.AppliedAt}
+	}
+	return applied, nil
+}
@@ -0,0 +1,236 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/datasource/sqlite3"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+}
+
+func openDB(t *testing.T, dir string) *sqlx.DB {
+	db, err := sqlite3.Init(filepath.Join(dir, "test.db"))
+	assert.NoError(t, err)
+	return db
+}
+
+func versions(migrations []Migration) []int64 {
+	var list []int64
+	for _, m := range migrations {
+		list = append(list, m.Version)
+	}
+	return list
+}
+
+func TestMigrator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "migrations")
+	assert.NoError(t, os.Mkdir(source, 0700))
+	writeFiles(t, source, map[string]string{
+		"0001_create_users.up.sql":   "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);",
+		"0001_create_users.down.sql": "DROP TABLE users;",
+		"0002_add_email.up.sql":      "ALTER TABLE users ADD COLUMN email TEXT; CREATE INDEX users_email ON users (email);",
+		"0002_add_email.down.sql":    "DROP INDEX users_email; CREATE TABLE users_old (id INTEGER PRIMARY KEY, name TEXT); DROP TABLE users; ALTER TABLE users_old RENAME TO users;",
+		"README.md":                  "ignored",
+	})
+
+	migrations, err := ReadDir(source)
+	if !assert.NoError(t, err) {
+		return
+	}
+	migrations = append(migrations, Migration{
+		Version: 3,
+		Name:    "seed_users",
+		Up: func(ctx context.Context, tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO users (name, email) VALUES ('ann', 'ann@example.com')")
+			return err
+		},
+		Down: func(ctx context.Context, tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, "DELETE FROM users")
+			return err
+		},
+	})
+
+	db := openDB(t, dir)
+	defer db.Close()
+	ctx := context.Background()
+
+	m, err := New(db, migrations, Options{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	status, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, status, 3)
+	assert.Equal(t, "create_users", status[0].Name)
+	assert.False(t, status[0].Applied)
+
+	// dry run doesn't change the database
+	dry, _ := New(db, migrations, Options{DryRun: true})
+	planned, err := dry.Up(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, versions(planned))
+	var tables int
+	assert.NoError(t, db.Get(&tables, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'"))
+	assert.Equal(t, 0, tables)
+
+	applied, err := m.Up(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, versions(applied))
+
+	applied, err = m.UpTo(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, versions(applied))
+
+	applied, err = m.Up(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3}, versions(applied))
+
+	var email string
+	assert.NoError(t, db.Get(&email, "SELECT email FROM users WHERE name = 'ann'"))
+	assert.Equal(t, "ann@example.com", email)
+
+	status, err = m.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range status {
+		assert.True(t, s.Applied, s.Name)
+		assert.False(t, s.AppliedAt.IsZero(), s.Name)
+	}
+
+	applied, err = m.Up(ctx, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	planned, err = dry.Down(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 2}, versions(planned))
+
+	rolledBack, err := m.Down(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 2}, versions(rolledBack))
+
+	var count int
+	assert.NoError(t, db.Get(&count, "SELECT COUNT(*) FROM users"))
+	assert.Equal(t, 0, count)
+	_, err = db.Exec("SELECT email FROM users")
+	assert.Error(t, err)
+
+	rolledBack, err = m.Down(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, versions(rolledBack))
+}
+
+func TestMigratorFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	db := openDB(t, dir)
+	defer db.Close()
+	ctx := context.Background()
+
+	m, err := New(db, []Migration{
+		{Version: 1, Name: "create_items", UpSQL: "CREATE TABLE items (id INTEGER)"},
+		{Version: 2, Name: "broken", UpSQL: "CREATE TABLE other (id INTEGER); INSERT INTO missing VALUES (1)"},
+	}, Options{Table: "migrations"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	applied, err := m.Up(ctx, 0)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "2_broken")
+	assert.Equal(t, []int64{1}, versions(applied))
+
+	// the failed migration is rolled back
+	var tables int
+	assert.NoError(t, db.Get(&tables, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'other'"))
+	assert.Equal(t, 0, tables)
+
+	status, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.True(t, status[0].Applied)
+	assert.False(t, status[1].Applied)
+
+	_, err = m.Down(ctx, 1)
+	assert.True(t, errors.Is(err, ErrNoDown))
+
+	// versions applied by a newer release
+	newer, _ := New(db, nil, Options{Table: "migrations"})
+	status, err = newer.Status(ctx)
+	assert.NoError(t, err)
+	assert.True(t, status[0].Missing)
+	_, err = newer.Down(ctx, 1)
+	assert.True(t, errors.Is(err, ErrMissingMigration))
+}
+
+func TestNewErrors(t *testing.T) {
+	db, err := sqlite3.Init(":memory:")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	_, err = New(db, []Migration{{Version: 1, UpSQL: "SELECT 1"}, {Version: 1, UpSQL: "SELECT 2"}}, Options{})
+	assert.True(t, errors.Is(err, ErrDuplicateVersion))
+
+	_, err = New(db, []Migration{{Version: 1}}, Options{})
+	assert.True(t, errors.Is(err, ErrNoUp))
+
+	_, err = New(db, nil, Options{Table: "users; DROP TABLE users"})
+	assert.Equal(t, ErrInvalidTable, err)
+}
+
+func TestReadDirErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{"create_users.sql": "CREATE TABLE users (id INTEGER)"})
+	_, err = ReadDir(dir)
+	assert.Error(t, err)
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "create_users.sql")))
+	writeFiles(t, dir, map[string]string{"0001_create_users.down.sql": "DROP TABLE users"})
+	_, err = ReadDir(dir)
+	assert.Error(t, err)
+}
+
+func TestSplitStatements(t *testing.T) {
+	query := `-- users
+CREATE TABLE users (id INT, name VARCHAR(10) DEFAULT 'a;b');
+/* seed; data */
+INSERT INTO users VALUES (1, "it's;"), (2, 'x\';y');
+# done
+UPDATE ` + "`users;`" + ` SET id = 3;;`
+
+	assert.Equal(t, []string{
+		"CREATE TABLE users (id INT, name VARCHAR(10) DEFAULT 'a;b')",
+		`INSERT INTO users VALUES (1, "it's;"), (2, 'x\';y')`,
+		"UPDATE `users;` SET id = 3",
+	}, splitStatements(query))
+
+	assert.Equal(t, []string{"SELECT 1; SELECT 2"}, statements("postgres", "SELECT 1; SELECT 2"))
+	assert.Equal(t, []string{"SELECT 1", "SELECT 2"}, statements("mysql", "SELECT 1; SELECT 2"))
+}
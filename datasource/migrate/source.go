@@ -0,0 +1,102 @@
+package migrate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// names of the SQL migration files, e.g. 0001_create_users.up.sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ReadDir returns the SQL migrations of the directory, sorted by version. Files
+// are named VERSION_NAME.up.sql and VERSION_NAME.down.sql, e.g.
+// 0001_create_users.up.sql; the down file is optional.
+func ReadDir(dir string) ([]Migration, error) {
+	return ReadFileSystem(http.Dir(dir), "/")
+}
+
+// ReadFileSystem is like ReadDir, but reads the files of dir in fs. Embedded
+// files can be read with http.FS:
+//
+//	//go:embed migrations
+//	var migrations embed.FS
+//
+//	list, err := migrate.ReadFileSystem(http.FS(migrations), "migrations")
+func ReadFileSystem(fs http.FileSystem, dir string) ([]Migration, error) {
+	d, err := fs.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	files, err := d.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		if file.IsDir() || path.Ext(file.Name()) != ".sql" {
+			continue
+		}
+
+		parts := fileName.FindStringSubmatch(file.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("migrate: invalid file name %s", file.Name())
+		}
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version in %s: %w", file.Name(), err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		} else if m.Name != parts[2] {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+
+		content, err := readFile(fs, path.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if parts[3] == "up" {
+			m.UpSQL = content
+		} else {
+			m.DownSQL = content
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.UpSQL == "" {
+			return nil, fmt.Errorf("migrate: missing up file of version %d", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sortMigrations(migrations)
+	return migrations, nil
+}
+
+func readFile(fs http.FileSystem, name string) (string, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	content, err := ioutil.ReadAll(f)
+	return string(content), err
+}
+
+func sortMigrations(migrations []Migration) {
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
@@ -0,0 +1,110 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func createTableQuery(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`, table)
+}
+
+// queries that return 1 if the table exists in the current database
+var tableExistsQueries = map[string]string{
+	"mysql":    "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+	"postgres": "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1",
+	"sqlite3":  "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+}
+
+func tableExists(ctx context.Context, db *sqlx.DB, table string) (bool, error) {
+	query, found := tableExistsQueries[db.DriverName()]
+	if !found {
+		return false, fmt.Errorf("migrate: driver %s not supported", db.DriverName())
+	}
+
+	var count int
+	err := db.GetContext(ctx, &count, query, table)
+	return count > 0, err
+}
+
+// statements splits the SQL of a migration for drivers that only run one
+// statement at a time (MySQL, unless multiStatements is set). The others run
+// it at once, so PostgreSQL functions with $$ bodies work.
+func statements(driverName, query string) []string {
+	if driverName != "mysql" {
+		return []string{query}
+	}
+	return splitStatements(query)
+}
+
+// splits query on the semicolons outside quotes and comments. Empty statements
+// are removed.
+func splitStatements(query string) []string {
+	var (
+		list    []string
+		current strings.Builder
+		quote   byte // quote character of the current string, if any
+	)
+
+	add := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			list = append(list, s)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' && i+1 < len(query) {
+				current.WriteByte(c)
+				i++
+				c = query[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && strings.HasPrefix(query[i:], "--"), c == '#':
+			// line comments are dropped
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				i = len(query)
+				continue
+			}
+			i += end
+			c = '\n'
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+				continue
+			}
+			i += end + 3
+			c = ' '
+		case c == ';':
+			add()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	add()
+
+	return list
+}
+
+func sortStatus(status []Status) {
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Version < status[j].Version
+	})
+}
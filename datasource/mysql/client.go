@@ -1,17 +1,234 @@
 package mysql
 
 import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"sync"
+	"time"
 
-	// import MySQL driver
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
+// TLS configurations registered in the driver
+var (
+	tlsMutex   sync.Mutex
+	tlsConfigs = make(map[*tls.Config]*tlsRegistration)
+	tlsCount   int // used to name them
+)
+
+type tlsRegistration struct {
+	name string
+	refs int // databases opened with the configuration
+}
+
+// Options holds the connection options of a MySQL database
+type Options struct {
+	Address  string
+	Username string
+	Password string
+	DBName   string
+
+	// TLSConfig enables TLS when it's not nil
+	TLSConfig *tls.Config
+
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+
+	// Params are the DSN parameters, e.g. charset, loc or system variables
+	Params map[string]string
+
+	// DriverName is the database/sql driver used to connect, e.g. one that
+	// instruments the MySQL driver. It's mysql if empty.
+	DriverName string
+}
+
 // Init connects to the database server
 func Init(address, username, password, dbName string) (db *sqlx.DB, err error) {
-	db, err = sqlx.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=True", username, password, address, dbName))
-	return
+	return Open(Options{Address: address, Username: username, Password: password, DBName: dbName})
+}
+
+// Open connects to the database server using the options. The TLS
+// configuration is deregistered from the driver when the database is closed.
+func Open(options Options) (db *sqlx.DB, err error) {
+	if options.TLSConfig != nil {
+		if err = acquireTLSConfig(options.TLSConfig); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				releaseTLSConfig(options.TLSConfig)
+			}
+		}()
+	}
+
+	dsn, err := DSN(options)
+	if err != nil {
+		return nil, err
+	}
+	return open(options.DriverName, dsn, options.TLSConfig)
+}
+
+// opens the database with driverName, binding the parameters like MySQL. If
+// tlsConfig is not nil, it's released when the database is closed.
+func open(driverName, dsn string, tlsConfig *tls.Config) (*sqlx.DB, error) {
+	if driverName == "" {
+		driverName = "mysql"
+	}
+
+	if tlsConfig == nil {
+		db, err := sql.Open(driverName, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return sqlx.NewDb(db, "mysql"), nil
+	}
+
+	connector, err := newConnector(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(&tlsConnector{Connector: connector, tlsConfig: tlsConfig})
+	return sqlx.NewDb(db, "mysql"), nil
+}
+
+// returns a connector for the dsn of the registered driver driverName
+func newConnector(driverName, dsn string) (driver.Connector, error) {
+	// opening doesn't connect, it's only used to get the driver
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	db.Close()
+
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return dsnConnector{driver: d, dsn: dsn}, nil
+}
+
+// dsnConnector is the connector of drivers that don't provide one
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (dc dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return dc.driver.Open(dc.dsn)
+}
+
+func (dc dsnConnector) Driver() driver.Driver {
+	return dc.driver
+}
+
+// tlsConnector releases the TLS configuration when the database is closed,
+// as database/sql closes the connectors that implement io.Closer
+type tlsConnector struct {
+	driver.Connector
+	tlsConfig *tls.Config
+	once      sync.Once
+}
+
+func (tc *tlsConnector) Close() error {
+	tc.once.Do(func() {
+		releaseTLSConfig(tc.tlsConfig)
+	})
+	return nil
+}
+
+// DSN returns the data source name of the options. The TLS configuration is
+// registered in the driver once, and used by the following calls with the same
+// *tls.Config; see DeregisterTLSConfig.
+func DSN(options Options) (string, error) {
+	config := mysql.NewConfig()
+	config.Net = "tcp"
+	config.Addr = options.Address
+	config.User = options.Username
+	config.Passwd = options.Password
+	config.DBName = options.DBName
+	config.ParseTime = true
+	config.Timeout = options.ConnectTimeout
+	config.ReadTimeout = options.ReadTimeout
+	config.WriteTimeout = options.WriteTimeout
+
+	if len(options.Params) > 0 {
+		config.Params = make(map[string]string, len(options.Params))
+		for k, v := range options.Params {
+			config.Params[k] = v
+		}
+	}
+
+	if options.TLSConfig != nil {
+		tlsMutex.Lock()
+		reg, err := registerTLSConfig(options.TLSConfig)
+		tlsMutex.Unlock()
+		if err != nil {
+			return "", err
+		}
+		config.TLSConfig = reg.name
+	}
+
+	return config.FormatDSN(), nil
+}
+
+// DeregisterTLSConfig removes the TLS configuration registered by DSN, unless
+// it's used by a database opened with Open. It's only needed when DSN is used
+// directly, as Open deregisters it when the database is closed.
+func DeregisterTLSConfig(tlsConfig *tls.Config) {
+	tlsMutex.Lock()
+	defer tlsMutex.Unlock()
+
+	if reg, found := tlsConfigs[tlsConfig]; found && reg.refs == 0 {
+		mysql.DeregisterTLSConfig(reg.name)
+		delete(tlsConfigs, tlsConfig)
+	}
+}
+
+// returns the registration of the TLS configuration in the driver, registering
+// it if needed; tlsMutex must be held
+func registerTLSConfig(tlsConfig *tls.Config) (*tlsRegistration, error) {
+	if reg, found := tlsConfigs[tlsConfig]; found {
+		return reg, nil
+	}
+
+	tlsCount++
+	reg := &tlsRegistration{name: fmt.Sprintf("lit-go-tools-%d", tlsCount)}
+	if err := mysql.RegisterTLSConfig(reg.name, tlsConfig); err != nil {
+		return nil, err
+	}
+	tlsConfigs[tlsConfig] = reg
+	return reg, nil
+}
+
+// registers the TLS configuration for a database, see releaseTLSConfig
+func acquireTLSConfig(tlsConfig *tls.Config) error {
+	tlsMutex.Lock()
+	defer tlsMutex.Unlock()
+
+	reg, err := registerTLSConfig(tlsConfig)
+	if err != nil {
+		return err
+	}
+	reg.refs++
+	return nil
+}
+
+// deregisters the TLS configuration when it's not used by any database
+func releaseTLSConfig(tlsConfig *tls.Config) {
+	tlsMutex.Lock()
+	defer tlsMutex.Unlock()
+
+	if reg, found := tlsConfigs[tlsConfig]; found {
+		if reg.refs--; reg.refs <= 0 {
+			mysql.DeregisterTLSConfig(reg.name)
+			delete(tlsConfigs, tlsConfig)
+		}
+	}
 }
 
 func RandFuncName() string {
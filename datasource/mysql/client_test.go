@@ -0,0 +1,93 @@
+package mysql
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDSNEscapesCredentials(t *testing.T) {
+	dsn, err := DSN(Options{
+		Address:  "db.local:3306",
+		Username: "app",
+		Password: "p@ss/w:rd?",
+		DBName:   "main",
+	})
+	assert.Nil(t, err)
+
+	config, err := mysql.ParseDSN(dsn)
+	assert.Nil(t, err)
+	assert.Equal(t, "app", config.User)
+	assert.Equal(t, "p@ss/w:rd?", config.Passwd)
+	assert.Equal(t, "db.local:3306", config.Addr)
+	assert.Equal(t, "main", config.DBName)
+	assert.True(t, config.ParseTime)
+}
+
+func TestDSNOptions(t *testing.T) {
+	dsn, err := DSN(Options{
+		Address:        "db.local:3306",
+		Username:       "app",
+		DBName:         "main",
+		TLSConfig:      &tls.Config{ServerName: "db.local"},
+		ConnectTimeout: 5 * time.Second,
+		ReadTimeout:    10 * time.Second,
+		WriteTimeout:   15 * time.Second,
+		Params:         map[string]string{"charset": "utf8mb4"},
+	})
+	assert.Nil(t, err)
+
+	config, err := mysql.ParseDSN(dsn)
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Second, config.Timeout)
+	assert.Equal(t, 10*time.Second, config.ReadTimeout)
+	assert.Equal(t, 15*time.Second, config.WriteTimeout)
+	assert.Equal(t, "utf8mb4", config.Params["charset"])
+	assert.Contains(t, config.TLSConfig, "lit-go-tools-")
+}
+
+func TestDSNTLSConfigRegisteredOnce(t *testing.T) {
+	options := Options{Address: "db.local:3306", TLSConfig: &tls.Config{ServerName: "db.local"}}
+
+	first, _ := DSN(options)
+	second, _ := DSN(options)
+	assert.Equal(t, first, second)
+
+	DeregisterTLSConfig(options.TLSConfig)
+	_, err := mysql.ParseDSN(first)
+	assert.Error(t, err)
+
+	third, _ := DSN(options)
+	assert.NotEqual(t, first, third)
+	DeregisterTLSConfig(options.TLSConfig)
+}
+
+func TestOpenReleasesTLSConfig(t *testing.T) {
+	// opening doesn't connect
+	options := Options{Address: "127.0.0.1:1", TLSConfig: &tls.Config{ServerName: "db.local"}}
+	db1, err := Open(options)
+	if !assert.NoError(t, err) {
+		return
+	}
+	db2, err := Open(options)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	dsn, _ := DSN(options)
+	DeregisterTLSConfig(options.TLSConfig)
+
+	// still used by db2
+	db1.Close()
+	_, err = mysql.ParseDSN(dsn)
+	assert.NoError(t, err)
+
+	db2.Close()
+	_, err = mysql.ParseDSN(dsn)
+	assert.Error(t, err)
+	_, found := tlsConfigs[options.TLSConfig]
+	assert.False(t, found)
+}
@@ -1,24 +1,97 @@
 package postgresql
 
 import (
-	"fmt"
+	"database/sql"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	// import PostgreSQL driver
 	_ "github.com/lib/pq"
 )
 
+// Options holds the connection options of a PostgreSQL database
+type Options struct {
+	Address  string
+	Username string
+	Password string
+	DBName   string
+
+	// SSLMode is disable, require, verify-ca or verify-full; the driver uses
+	// require if it's empty
+	SSLMode     string
+	SSLRootCert string // CA file
+	SSLCert     string
+	SSLKey      string
+
+	// ConnectTimeout is rounded to seconds
+	ConnectTimeout time.Duration
+
+	// Params are the DSN parameters, e.g. application_name or search_path
+	Params map[string]string
+
+	// DriverName is the database/sql driver used to connect, e.g. one that
+	// instruments the PostgreSQL driver. It's postgres if empty.
+	DriverName string
+}
+
 // Init connects to the database server
 func Init(address, username, password string, dbName string) (db *sqlx.DB, err error) {
+	return Open(Options{Address: address, Username: username, Password: password, DBName: dbName})
+}
+
+// Open connects to the database server using the options, and pings it
+func Open(options Options) (db *sqlx.DB, err error) {
+
+	driverName := options.DriverName
+	if driverName == "" {
+		driverName = "postgres"
+	}
 
-	db, err = sqlx.Open("postgres", fmt.Sprintf("postgres://%s:%s@%v/%s", username, password, address, dbName))
-	if err == nil {
-		err = db.Ping()
+	sqlDB, err := sql.Open(driverName, DSN(options))
+	if err != nil {
+		return nil, err
 	}
 
+	// parameters are bound like in PostgreSQL, whatever the driver name
+	db = sqlx.NewDb(sqlDB, "postgres")
+	err = db.Ping()
 	return
 }
 
+// DSN returns the connection URL of the options, with all the parts escaped
+func DSN(options Options) string {
+	query := url.Values{}
+	for k, v := range options.Params {
+		query.Set(k, v)
+	}
+
+	setParam(query, "sslmode", options.SSLMode)
+	setParam(query, "sslrootcert", options.SSLRootCert)
+	setParam(query, "sslcert", options.SSLCert)
+	setParam(query, "sslkey", options.SSLKey)
+	if options.ConnectTimeout > 0 {
+		seconds := int((options.ConnectTimeout + time.Second - 1) / time.Second)
+		query.Set("connect_timeout", strconv.Itoa(seconds))
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(options.Username, options.Password),
+		Host:     options.Address,
+		Path:     "/" + options.DBName,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+func setParam(query url.Values, name, value string) {
+	if value != "" {
+		query.Set(name, value)
+	}
+}
+
 func RandFuncName() string {
-	return "rand()"
+	return "random()"
 }
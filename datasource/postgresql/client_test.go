@@ -0,0 +1,48 @@
+package postgresql
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDSNEscapesCredentials(t *testing.T) {
+	dsn := DSN(Options{
+		Address:  "db.local:5432",
+		Username: "app",
+		Password: "p@ss/w:rd?",
+		DBName:   "main",
+	})
+
+	u, err := url.Parse(dsn)
+	assert.Nil(t, err)
+	assert.Equal(t, "app", u.User.Username())
+	password, _ := u.User.Password()
+	assert.Equal(t, "p@ss/w:rd?", password)
+	assert.Equal(t, "db.local:5432", u.Host)
+	assert.Equal(t, "/main", u.Path)
+	assert.Equal(t, "", u.RawQuery)
+}
+
+func TestDSNOptions(t *testing.T) {
+	dsn := DSN(Options{
+		Address:        "db.local:5432",
+		Username:       "app",
+		DBName:         "main",
+		SSLMode:        "verify-full",
+		SSLRootCert:    "/etc/ssl/ca.pem",
+		ConnectTimeout: 1500 * time.Millisecond,
+		Params:         map[string]string{"application_name": "my app"},
+	})
+
+	u, err := url.Parse(dsn)
+	assert.Nil(t, err)
+	query := u.Query()
+	assert.Equal(t, "verify-full", query.Get("sslmode"))
+	assert.Equal(t, "/etc/ssl/ca.pem", query.Get("sslrootcert"))
+	assert.Equal(t, "2", query.Get("connect_timeout"))
+	assert.Equal(t, "my app", query.Get("application_name"))
+	assert.Equal(t, "", query.Get("sslcert"))
+}
@@ -1,32 +1,305 @@
 package redis
 
 import (
+	"context"
+	"crypto/tls"
+	"sync"
 	"time"
 
-	rds "github.com/go-redis/redis"
+	rds "github.com/go-redis/redis/v8"
 )
 
-// Redis wrapper.
+// scripts that check the value of the key before changing it
+var (
+	compareAndDeleteScript = rds.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	compareAndExpireScript = rds.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// Redis wrapper. The client, and its connection pool, is created on first use
+// and shared by all the operations until Close is called. Operations are
+// cancelled when their context is done, and driver errors are wrapped (see Error).
 type Redis struct {
 	Address  string
 	Password string
+	DB       int
+
+	// TLSConfig enables TLS when it's not nil
+	TLSConfig *tls.Config
+
+	// timeouts; zero uses go-redis defaults
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// pool options; zero uses go-redis defaults
+	PoolSize     int
+	MinIdleConns int
+	PoolTimeout  time.Duration
+	IdleTimeout  time.Duration
+	MaxRetries   int
+
+	once   sync.Once
+	client *rds.Client
 }
 
-func (r Redis) newClient() *rds.Client {
-	return rds.NewClient(&rds.Options{
-		Addr:     r.Address,
-		Password: r.Password,
+// Client returns the underlying go-redis client, creating it on first call
+func (r *Redis) Client() *rds.Client {
+	r.once.Do(func() {
+		r.client = rds.NewClient(&rds.Options{
+			Addr:         r.Address,
+			Password:     r.Password,
+			DB:           r.DB,
+			TLSConfig:    r.TLSConfig,
+			DialTimeout:  r.DialTimeout,
+			ReadTimeout:  r.ReadTimeout,
+			WriteTimeout: r.WriteTimeout,
+			PoolSize:     r.PoolSize,
+			MinIdleConns: r.MinIdleConns,
+			PoolTimeout:  r.PoolTimeout,
+			IdleTimeout:  r.IdleTimeout,
+			MaxRetries:   r.MaxRetries,
+		})
 	})
+	return r.client
 }
 
-// Get returns a value associated with the provided key.
-func (r Redis) Get(key string) ([]byte, error) {
-	client := r.newClient()
-	return client.Get(key).Bytes()
+// Ping checks the connection with the server
+func (r *Redis) Ping(ctx context.Context) error {
+	return wrapError(r.Client().Ping(ctx).Err())
+}
+
+// Close releases the connections of the pool. Operations fail with
+// ErrConnection after closing it.
+func (r *Redis) Close() error {
+	// creating the client doesn't connect, and it's closed afterwards
+	return r.Client().Close()
+}
+
+// Get returns a value associated with the provided key, or ErrKeyNotFound.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := r.Client().Get(ctx, key).Bytes()
+	return v, wrapError(err)
 }
 
 // Set sets a value for the provided key.
-func (r Redis) Set(key string, value interface{}, expiration time.Duration) error {
-	client := r.newClient()
-	return client.Set(key, value, expiration).Err()
+func (r *Redis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return wrapError(r.Client().Set(ctx, key, value, expiration).Err())
+}
+
+// SetNX sets a value for the provided key only if it doesn't exist. It returns
+// true if the value was set.
+func (r *Redis) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	v, err := r.Client().SetNX(ctx, key, value, expiration).Result()
+	return v, wrapError(err)
+}
+
+// Delete removes the keys, returning the number of keys removed.
+func (r *Redis) Delete(ctx context.Context, keys ...string) (int64, error) {
+	v, err := r.Client().Del(ctx, keys...).Result()
+	return v, wrapError(err)
+}
+
+// Exists returns how many of the keys exist.
+func (r *Redis) Exists(ctx context.Context, keys ...string) (int64, error) {
+	v, err := r.Client().Exists(ctx, keys...).Result()
+	return v, wrapError(err)
+}
+
+// Expire sets the expiration of the key. It returns false if the key doesn't exist.
+func (r *Redis) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	v, err := r.Client().Expire(ctx, key, expiration).Result()
+	return v, wrapError(err)
+}
+
+// TTL returns the remaining time to live of the key; -1s if the key has no
+// expiration, and -2s if it doesn't exist.
+func (r *Redis) TTL(ctx context.Context, key string) (time.Duration, error) {
+	v, err := r.Client().TTL(ctx, key).Result()
+	if err != nil {
+		return 0, wrapError(err)
+	}
+
+	// go-redis returns the -1 and -2 replies as nanoseconds
+	if v < 0 {
+		v *= time.Second
+	}
+	return v, nil
+}
+
+// Incr increments the integer value of the key by one, returning the new value.
+func (r *Redis) Incr(ctx context.Context, key string) (int64, error) {
+	v, err := r.Client().Incr(ctx, key).Result()
+	return v, wrapError(err)
+}
+
+// IncrBy increments the integer value of the key by value.
+func (r *Redis) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	v, err := r.Client().IncrBy(ctx, key, value).Result()
+	return v, wrapError(err)
+}
+
+// Decr decrements the integer value of the key by one, returning the new value.
+func (r *Redis) Decr(ctx context.Context, key string) (int64, error) {
+	v, err := r.Client().Decr(ctx, key).Result()
+	return v, wrapError(err)
+}
+
+// DecrBy decrements the integer value of the key by value.
+func (r *Redis) DecrBy(ctx context.Context, key string, value int64) (int64, error) {
+	v, err := r.Client().DecrBy(ctx, key, value).Result()
+	return v, wrapError(err)
+}
+
+// MGet returns the values of the keys, in the same order; missing keys have a
+// nil value.
+func (r *Redis) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	result, err := r.Client().MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	values := make([][]byte, len(result))
+	for i, v := range result {
+		if s, ok := v.(string); ok {
+			values[i] = []byte(s)
+		}
+	}
+	return values, nil
+}
+
+// MSet sets the values of several keys at once.
+func (r *Redis) MSet(ctx context.Context, values map[string]interface{}) error {
+	pairs := make([]interface{}, 0, len(values)*2)
+	for k, v := range values {
+		pairs = append(pairs, k, v)
+	}
+	return wrapError(r.Client().MSet(ctx, pairs...).Err())
+}
+
+// CompareAndDelete removes the key only if its value is value. It returns true
+// if the key was removed.
+func (r *Redis) CompareAndDelete(ctx context.Context, key string, value interface{}) (bool, error) {
+	n, err := compareAndDeleteScript.Run(ctx, r.Client(), []string{key}, value).Int64()
+	return n == 1, wrapError(err)
+}
+
+// CompareAndExpire sets the expiration of the key only if its value is value.
+// It returns true if the expiration was set.
+func (r *Redis) CompareAndExpire(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	n, err := compareAndExpireScript.Run(ctx, r.Client(), []string{key}, value, expiration.Milliseconds()).Int64()
+	return n == 1, wrapError(err)
+}
+
+// HSet sets the field of the hash stored at key.
+func (r *Redis) HSet(ctx context.Context, key, field string, value interface{}) error {
+	return wrapError(r.Client().HSet(ctx, key, field, value).Err())
+}
+
+// HMSet sets several fields of the hash stored at key.
+func (r *Redis) HMSet(ctx context.Context, key string, fields map[string]interface{}) error {
+	return wrapError(r.Client().HMSet(ctx, key, fields).Err())
+}
+
+// HGet returns the value of the field of the hash stored at key, or
+// ErrKeyNotFound if the key or the field don't exist.
+func (r *Redis) HGet(ctx context.Context, key, field string) ([]byte, error) {
+	v, err := r.Client().HGet(ctx, key, field).Bytes()
+	return v, wrapError(err)
+}
+
+// HGetAll returns all the fields of the hash stored at key.
+func (r *Redis) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	v, err := r.Client().HGetAll(ctx, key).Result()
+	return v, wrapError(err)
+}
+
+// HExists returns true if the field exists in the hash stored at key.
+func (r *Redis) HExists(ctx context.Context, key, field string) (bool, error) {
+	v, err := r.Client().HExists(ctx, key, field).Result()
+	return v, wrapError(err)
+}
+
+// HDel removes the fields from the hash stored at key, returning the number of
+// fields removed.
+func (r *Redis) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	v, err := r.Client().HDel(ctx, key, fields...).Result()
+	return v, wrapError(err)
+}
+
+// LPush inserts the values at the head of the list, returning its length.
+func (r *Redis) LPush(ctx context.Context, key string, values ...interface{}) (int64, error) {
+	v, err := r.Client().LPush(ctx, key, values...).Result()
+	return v, wrapError(err)
+}
+
+// RPush inserts the values at the tail of the list, returning its length.
+func (r *Redis) RPush(ctx context.Context, key string, values ...interface{}) (int64, error) {
+	v, err := r.Client().RPush(ctx, key, values...).Result()
+	return v, wrapError(err)
+}
+
+// LPop removes and returns the first element of the list, or ErrKeyNotFound
+// if the list is empty.
+func (r *Redis) LPop(ctx context.Context, key string) ([]byte, error) {
+	v, err := r.Client().LPop(ctx, key).Bytes()
+	return v, wrapError(err)
+}
+
+// RPop removes and returns the last element of the list, or ErrKeyNotFound if
+// the list is empty.
+func (r *Redis) RPop(ctx context.Context, key string) ([]byte, error) {
+	v, err := r.Client().RPop(ctx, key).Bytes()
+	return v, wrapError(err)
+}
+
+// LRange returns the elements of the list between start and stop, inclusive.
+// Negative indexes are offsets from the end of the list.
+func (r *Redis) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	v, err := r.Client().LRange(ctx, key, start, stop).Result()
+	return v, wrapError(err)
+}
+
+// LLen returns the length of the list.
+func (r *Redis) LLen(ctx context.Context, key string) (int64, error) {
+	v, err := r.Client().LLen(ctx, key).Result()
+	return v, wrapError(err)
+}
+
+// SAdd adds the members to the set, returning the number of members added.
+func (r *Redis) SAdd(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	v, err := r.Client().SAdd(ctx, key, members...).Result()
+	return v, wrapError(err)
+}
+
+// SRem removes the members from the set, returning the number of members removed.
+func (r *Redis) SRem(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	v, err := r.Client().SRem(ctx, key, members...).Result()
+	return v, wrapError(err)
+}
+
+// SMembers returns all the members of the set.
+func (r *Redis) SMembers(ctx context.Context, key string) ([]string, error) {
+	v, err := r.Client().SMembers(ctx, key).Result()
+	return v, wrapError(err)
+}
+
+// SIsMember returns true if member belongs to the set.
+func (r *Redis) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
+	v, err := r.Client().SIsMember(ctx, key, member).Result()
+	return v, wrapError(err)
+}
+
+// SCard returns the number of members of the set.
+func (r *Redis) SCard(ctx context.Context, key string) (int64, error) {
+	v, err := r.Client().SCard(ctx, key).Result()
+	return v, wrapError(err)
 }
@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// returns a client connected to an in-process server
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Redis{Address: server.Addr()}, server
+}
+
+func TestTTL(t *testing.T) {
+	r, server := newTestRedis(t)
+	defer server.Close()
+	defer r.Close()
+
+	ctx := context.Background()
+	assert.NoError(t, r.Set(ctx, "a", "1", time.Minute))
+	assert.NoError(t, r.Set(ctx, "b", "1", 0))
+
+	ttl, err := r.TTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	// same values returned by MemoryDB
+	ttl, _ = r.TTL(ctx, "b")
+	assert.Equal(t, -time.Second, ttl)
+	ttl, _ = r.TTL(ctx, "missing")
+	assert.Equal(t, -2*time.Second, ttl)
+}
@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	rds "github.com/go-redis/redis/v8"
+)
+
+// Errors returned by the operations, so callers can tell apart the failures
+// without importing the driver. Driver errors are wrapped, so errors.Is works
+// with both these values and the original error.
+var (
+	ErrKeyNotFound  = errors.New("key not found")
+	ErrConnection   = errors.New("connection error")
+	ErrTimeout      = errors.New("timeout")
+	ErrTypeMismatch = errors.New("type mismatch")
+)
+
+// Error wraps a driver error with its kind (ErrConnection, ErrTimeout...)
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the driver error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true if target is the kind of the error
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// classifies the driver errors
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if err == rds.Nil {
+		return ErrKeyNotFound
+	}
+
+	kind := errorKind(err)
+	if kind == nil {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+func errorKind(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.Is(err, context.Canceled):
+		// cancellation is reported as is
+		return nil
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrTimeout
+		}
+		return ErrConnection
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, rds.ErrClosed):
+		return ErrConnection
+	}
+
+	msg := err.Error()
+	switch {
+	case msg == "redis: connection pool timeout":
+		return ErrTimeout
+	case strings.HasPrefix(msg, "WRONGTYPE"),
+		strings.Contains(msg, "not an integer"),
+		strings.Contains(msg, "not a valid float"),
+		strings.HasPrefix(msg, "redis: can't parse"):
+		return ErrTypeMismatch
+	}
+
+	return nil
+}
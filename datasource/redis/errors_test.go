@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	rds "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapError(t *testing.T) {
+	assert.Nil(t, wrapError(nil))
+	assert.Equal(t, ErrKeyNotFound, wrapError(rds.Nil))
+
+	err := wrapError(context.DeadlineExceeded)
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	assert.Equal(t, context.Canceled, wrapError(context.Canceled))
+	assert.True(t, errors.Is(wrapError(io.EOF), ErrConnection))
+	assert.True(t, errors.Is(wrapError(&net.OpError{Op: "dial", Err: errors.New("refused")}), ErrConnection))
+	assert.True(t, errors.Is(wrapError(errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")), ErrTypeMismatch))
+	assert.True(t, errors.Is(wrapError(errors.New("ERR value is not an integer or out of range")), ErrTypeMismatch))
+}
+
+func TestClosedClient(t *testing.T) {
+	r := &Redis{Address: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1}
+
+	_, err := r.Get(context.Background(), "key")
+	assert.True(t, errors.Is(err, ErrConnection))
+
+	assert.NoError(t, r.Close())
+	assert.True(t, errors.Is(r.Set(context.Background(), "key", 1, 0), ErrConnection))
+}
@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	rds "github.com/go-redis/redis/v8"
+)
+
+// wait before receiving again after an error, while the client reconnects
+const receiveRetryInterval = time.Second
+
+// Message is a message received from a channel
+type Message struct {
+	Channel string
+	Pattern string // set when subscribed with PSubscribe
+	Payload string
+}
+
+// Subscription delivers the messages of the subscribed channels. go-redis
+// reconnects and subscribes again to the channels when the connection is lost,
+// so messages keep arriving until Close is called; messages published while
+// disconnected are lost.
+type Subscription struct {
+	pubsub   *rds.PubSub
+	messages chan Message
+	cancel   context.CancelFunc
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Publish sends the message to the channel, returning the number of clients
+// that received it.
+func (r *Redis) Publish(ctx context.Context, channel string, message interface{}) (int64, error) {
+	n, err := r.Client().Publish(ctx, channel, message).Result()
+	return n, wrapError(err)
+}
+
+// Subscribe starts receiving the messages of the channels. The subscription is
+// confirmed before returning.
+func (r *Redis) Subscribe(ctx context.Context, channels ...string) (*Subscription, error) {
+	return newSubscription(ctx, r.Client().Subscribe(ctx, channels...))
+}
+
+// PSubscribe starts receiving the messages of the channels that match the
+// patterns (e.g. "events.*").
+func (r *Redis) PSubscribe(ctx context.Context, patterns ...string) (*Subscription, error) {
+	return newSubscription(ctx, r.Client().PSubscribe(ctx, patterns...))
+}
+
+func newSubscription(ctx context.Context, pubsub *rds.PubSub) (*Subscription, error) {
+	// wait for the confirmation, so errors are reported here
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, wrapError(err)
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	s := &Subscription{
+		pubsub:   pubsub,
+		messages: make(chan Message, 100),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go s.receive(loopCtx)
+
+	return s, nil
+}
+
+func (s *Subscription) receive(ctx context.Context) {
+	defer close(s.done)
+	defer close(s.messages)
+
+	for {
+		msg, err := s.pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || err == rds.ErrClosed {
+				return
+			}
+
+			// the client reconnects and subscribes again on the next receive
+			select {
+			case <-time.After(receiveRetryInterval):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case s.messages <- Message{Channel: msg.Channel, Pattern: msg.Pattern, Payload: msg.Payload}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Messages returns the channel of received messages. It's closed after Close.
+func (s *Subscription) Messages() <-chan Message {
+	return s.messages
+}
+
+// Close unsubscribes and waits until the messages channel is closed
+func (s *Subscription) Close() (err error) {
+	s.once.Do(func() {
+		s.cancel()
+		err = s.pubsub.Close()
+		<-s.done
+	})
+	return wrapError(err)
+}
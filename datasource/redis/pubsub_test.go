@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// returns the next message, or fails after a second
+func receiveMessage(t *testing.T, s *Subscription) Message {
+	select {
+	case msg := <-s.Messages():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+		return Message{}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	r, server := newTestRedis(t)
+	defer server.Close()
+	defer r.Close()
+
+	ctx := context.Background()
+	s, err := r.Subscribe(ctx, "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := r.Publish(ctx, "events", "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	r.Publish(ctx, "other", "ignored")
+	r.Publish(ctx, "events", "bye")
+
+	assert.Equal(t, Message{Channel: "events", Payload: "hello"}, receiveMessage(t, s))
+	assert.Equal(t, Message{Channel: "events", Payload: "bye"}, receiveMessage(t, s))
+
+	assert.NoError(t, s.Close())
+	_, open := <-s.Messages()
+	assert.False(t, open)
+	assert.NoError(t, s.Close())
+}
+
+func TestPSubscribe(t *testing.T) {
+	r, server := newTestRedis(t)
+	defer server.Close()
+	defer r.Close()
+
+	ctx := context.Background()
+	s, err := r.PSubscribe(ctx, "events.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	r.Publish(ctx, "other", "ignored")
+	r.Publish(ctx, "events.users", "created")
+
+	assert.Equal(t, Message{Channel: "events.users", Pattern: "events.*", Payload: "created"}, receiveMessage(t, s))
+}
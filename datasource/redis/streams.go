@@ -0,0 +1,342 @@
+package redis
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rds "github.com/go-redis/redis/v8"
+)
+
+// StreamMessage is an entry of a stream
+type StreamMessage struct {
+	Stream string
+	ID     string
+	Values map[string]interface{}
+}
+
+// ConsumerOptions holds the configuration of a stream Consumer. Zero values use
+// the defaults.
+type ConsumerOptions struct {
+	Stream   string
+	Group    string
+	Consumer string // name of this consumer in the group, e.g. the host name
+
+	// StartID is where the group starts reading when it's created: "$" (default)
+	// for new messages only, or "0" for the whole stream
+	StartID string
+
+	// Count is the max number of messages read at once (10 by default)
+	Count int64
+
+	// Block is the time a read waits for new messages (2s by default); Close may
+	// take that long
+	Block time.Duration
+
+	// MinIdle is the time after which messages delivered to other consumers and
+	// not acknowledged are claimed by this one (1 minute by default)
+	MinIdle time.Duration
+
+	// ClaimInterval is the time between the checks of pending messages of
+	// other consumers (MinIdle by default)
+	ClaimInterval time.Duration
+}
+
+func (o *ConsumerOptions) setDefaults() {
+	if o.StartID == "" {
+		o.StartID = "$"
+	}
+	if o.Count <= 0 {
+		o.Count = 10
+	}
+	if o.Block <= 0 {
+		o.Block = 2 * time.Second
+	}
+	if o.MinIdle <= 0 {
+		o.MinIdle = time.Minute
+	}
+	if o.ClaimInterval <= 0 {
+		o.ClaimInterval = o.MinIdle
+	}
+}
+
+// Consumer reads a stream as a member of a consumer group. Messages must be
+// acknowledged with Ack once processed; otherwise they're delivered again
+// after a restart, or claimed by another consumer after MinIdle. Delivery is
+// at least once: a message may be received again (e.g. if it's claimed while
+// its first consumer is still processing it), so processing should be
+// idempotent.
+type Consumer struct {
+	redis    *Redis
+	options  ConsumerOptions
+	messages chan StreamMessage
+	errors   chan error
+	cancel   context.CancelFunc
+	done     chan struct{}
+	once     sync.Once
+}
+
+// XAdd appends the values to the stream, returning the ID of the new entry. If
+// maxLen is positive, the stream is trimmed to approximately that length.
+func (r *Redis) XAdd(ctx context.Context, stream string, values map[string]interface{}, maxLen int64) (string, error) {
+	args := &rds.XAddArgs{Stream: stream, Values: values}
+	if maxLen > 0 {
+		args.MaxLen = maxLen
+		args.Approx = true
+	}
+
+	id, err := r.Client().XAdd(ctx, args).Result()
+	return id, wrapError(err)
+}
+
+// XAck acknowledges the messages of the group, returning how many were pending.
+func (r *Redis) XAck(ctx context.Context, stream, group string, ids ...string) (int64, error) {
+	n, err := r.Client().XAck(ctx, stream, group, ids...).Result()
+	return n, wrapError(err)
+}
+
+// Consume creates the consumer group if it doesn't exist, and starts reading
+// the stream. The messages pending for this consumer, from a previous run, are
+// delivered first.
+func (r *Redis) Consume(ctx context.Context, options ConsumerOptions) (*Consumer, error) {
+	options.setDefaults()
+
+	err := r.Client().XGroupCreateMkStream(ctx, options.Stream, options.Group, options.StartID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, wrapError(err)
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	c := &Consumer{
+		redis:    r,
+		options:  options,
+		messages: make(chan StreamMessage, options.Count),
+		errors:   make(chan error, 1),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go c.consume(loopCtx)
+
+	return c, nil
+}
+
+// Messages returns the channel of received messages. It's closed after Close.
+func (c *Consumer) Messages() <-chan StreamMessage {
+	return c.messages
+}
+
+// Errors returns the errors found reading the stream; the consumer keeps
+// retrying. Only the last error is kept if they're not received.
+func (c *Consumer) Errors() <-chan error {
+	return c.errors
+}
+
+// Ack acknowledges the processed messages
+func (c *Consumer) Ack(ctx context.Context, ids ...string) error {
+	_, err := c.redis.XAck(ctx, c.options.Stream, c.options.Group, ids...)
+	return err
+}
+
+// Close stops reading and waits until the messages channel is closed. Messages
+// not acknowledged remain pending.
+func (c *Consumer) Close() error {
+	c.once.Do(func() {
+		c.cancel()
+		<-c.done
+	})
+	return nil
+}
+
+func (c *Consumer) consume(ctx context.Context) {
+	defer close(c.done)
+	defer close(c.messages)
+
+	// pending messages of this consumer first, then new ones (">")
+	id := "0"
+	lastClaim := time.Now()
+
+	// messages claimed while the pending ones are read, which are in the
+	// pending list of this consumer too, so they're skipped when read again
+	claimed := make(map[string]bool)
+
+	// next page of the pending list to check, "" if there's no scan in
+	// progress; a scan that fails is resumed from the same page
+	claimStart := ""
+
+	for ctx.Err() == nil {
+		if claimStart == "" && time.Since(lastClaim) >= c.options.ClaimInterval {
+			lastClaim = time.Now()
+			claimStart = "-"
+		}
+		if claimStart != "" {
+			var ok bool
+			if claimStart, ok = c.claim(ctx, claimStart, claimed); !ok {
+				return
+			}
+		}
+
+		streams, err := c.redis.Client().XReadGroup(ctx, &rds.XReadGroupArgs{
+			Group:    c.options.Group,
+			Consumer: c.options.Consumer,
+			Streams:  []string{c.options.Stream, id},
+			Count:    c.options.Count,
+			Block:    c.options.Block,
+		}).Result()
+
+		if err == rds.Nil {
+			continue
+		}
+		if err != nil {
+			c.fail(ctx, err)
+			continue
+		}
+
+		for _, stream := range streams {
+			messages := stream.Messages
+			if id != ">" {
+				if len(messages) == 0 {
+					// no more pending messages
+					id = ">"
+					claimed = nil
+				} else {
+					id = messages[len(messages)-1].ID
+					messages = skipClaimed(messages, claimed)
+				}
+			}
+			if !c.deliver(ctx, messages) {
+				return
+			}
+		}
+	}
+}
+
+// claims and delivers the messages of other consumers idle for longer than
+// MinIdle, checking the pending list from start, Count entries at a time. It
+// returns where the scan must be resumed if it failed ("" once it's complete),
+// and false if the consumer is closed. The IDs claimed are added to claimed,
+// unless it's nil.
+func (c *Consumer) claim(ctx context.Context, start string, claimed map[string]bool) (string, bool) {
+	for {
+		pending, err := c.redis.Client().XPendingExt(ctx, &rds.XPendingExtArgs{
+			Stream: c.options.Stream,
+			Group:  c.options.Group,
+			Start:  start,
+			End:    "+",
+			Count:  c.options.Count,
+		}).Result()
+		if err != nil {
+			c.fail(ctx, err)
+			return start, ctx.Err() == nil
+		}
+
+		var ids []string
+		for _, p := range pending {
+			if p.Consumer != c.options.Consumer && p.Idle >= c.options.MinIdle {
+				ids = append(ids, p.ID)
+			}
+		}
+
+		if len(ids) > 0 {
+			messages, err := c.redis.Client().XClaim(ctx, &rds.XClaimArgs{
+				Stream:   c.options.Stream,
+				Group:    c.options.Group,
+				Consumer: c.options.Consumer,
+				MinIdle:  c.options.MinIdle,
+				Messages: ids,
+			}).Result()
+			if err != nil {
+				c.fail(ctx, err)
+				return start, ctx.Err() == nil
+			}
+			if claimed != nil {
+				for _, msg := range messages {
+					claimed[msg.ID] = true
+				}
+			}
+			if !c.deliver(ctx, messages) {
+				return "", false
+			}
+		}
+
+		if int64(len(pending)) < c.options.Count {
+			return "", true
+		}
+
+		var ok bool
+		if start, ok = nextStreamID(pending[len(pending)-1].ID); !ok {
+			return "", true
+		}
+	}
+}
+
+// returns the messages not claimed, removing the rest from claimed since each
+// one is read once while replaying
+func skipClaimed(messages []rds.XMessage, claimed map[string]bool) []rds.XMessage {
+	if len(claimed) == 0 {
+		return messages
+	}
+
+	kept := make([]rds.XMessage, 0, len(messages))
+	for _, msg := range messages {
+		if claimed[msg.ID] {
+			delete(claimed, msg.ID)
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept
+}
+
+// returns the smallest stream ID greater than id, so ranges can be paginated
+// without the exclusive ranges of Redis 6.2. It returns false if id is not a
+// valid ID.
+func nextStreamID(id string) (string, bool) {
+	i := strings.IndexByte(id, '-')
+	if i < 0 {
+		return "", false
+	}
+
+	ms, errMs := strconv.ParseUint(id[:i], 10, 64)
+	seq, errSeq := strconv.ParseUint(id[i+1:], 10, 64)
+	if errMs != nil || errSeq != nil {
+		return "", false
+	}
+	if seq == math.MaxUint64 {
+		return strconv.FormatUint(ms+1, 10) + "-0", true
+	}
+	return strconv.FormatUint(ms, 10) + "-" + strconv.FormatUint(seq+1, 10), true
+}
+
+// sends the messages to the channel, returning false if the consumer is closed
+func (c *Consumer) deliver(ctx context.Context, messages []rds.XMessage) bool {
+	for _, msg := range messages {
+		select {
+		case c.messages <- StreamMessage{Stream: c.options.Stream, ID: msg.ID, Values: msg.Values}:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// reports the error and waits before retrying
+func (c *Consumer) fail(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	// keep only the last error
+	select {
+	case <-c.errors:
+	default:
+	}
+	c.errors <- wrapError(err)
+
+	select {
+	case <-time.After(receiveRetryInterval):
+	case <-ctx.Done():
+	}
+}
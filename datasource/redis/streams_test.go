@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	rds "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// receives n messages, or fails after a second
+func receiveStream(t *testing.T, c *Consumer, n int) []StreamMessage {
+	var messages []StreamMessage
+	for len(messages) < n {
+		select {
+		case msg := <-c.Messages():
+			messages = append(messages, msg)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d messages out of %d", len(messages), n)
+		}
+	}
+	return messages
+}
+
+func TestConsumer(t *testing.T) {
+	r, server := newTestRedis(t)
+	defer server.Close()
+	defer r.Close()
+
+	ctx := context.Background()
+	c, err := r.Consume(ctx, ConsumerOptions{Stream: "jobs", Group: "workers", Consumer: "a", Block: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := r.XAdd(ctx, "jobs", map[string]interface{}{"job": "1"}, 0)
+	assert.NoError(t, err)
+
+	messages := receiveStream(t, c, 1)
+	assert.Equal(t, StreamMessage{Stream: "jobs", ID: id, Values: map[string]interface{}{"job": "1"}}, messages[0])
+
+	pending, _ := r.Client().XPending(ctx, "jobs", "workers").Result()
+	assert.Equal(t, int64(1), pending.Count)
+
+	assert.NoError(t, c.Ack(ctx, id))
+	pending, _ = r.Client().XPending(ctx, "jobs", "workers").Result()
+	assert.Equal(t, int64(0), pending.Count)
+
+	assert.NoError(t, c.Close())
+	_, open := <-c.Messages()
+	assert.False(t, open)
+}
+
+func TestConsumerPendingFirst(t *testing.T) {
+	r, server := newTestRedis(t)
+	defer server.Close()
+	defer r.Close()
+
+	ctx := context.Background()
+	options := ConsumerOptions{Stream: "jobs", Group: "workers", Consumer: "a", StartID: "0", Block: 50 * time.Millisecond}
+
+	r.XAdd(ctx, "jobs", map[string]interface{}{"job": "1"}, 0)
+	c, err := r.Consume(ctx, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := receiveStream(t, c, 1)[0]
+	c.Close()
+
+	// not acknowledged, delivered again after a restart
+	c, err = r.Consume(ctx, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	assert.Equal(t, first.ID, receiveStream(t, c, 1)[0].ID)
+}
+
+func TestConsumerClaim(t *testing.T) {
+	r, server := newTestRedis(t)
+	defer server.Close()
+	defer r.Close()
+
+	ctx := context.Background()
+	r.Client().XGroupCreateMkStream(ctx, "jobs", "workers", "0")
+
+	// more entries than a page of the pending list, read by a consumer that
+	// never acknowledges them
+	var ids []string
+	for i := 0; i < 25; i++ {
+		id, _ := r.XAdd(ctx, "jobs", map[string]interface{}{"job": fmt.Sprint(i)}, 0)
+		ids = append(ids, id)
+	}
+	r.Client().XReadGroup(ctx, &rds.XReadGroupArgs{Group: "workers", Consumer: "dead", Streams: []string{"jobs", ">"}})
+
+	server.SetTime(time.Now().Add(time.Hour))
+
+	c, err := r.Consume(ctx, ConsumerOptions{
+		Stream:        "jobs",
+		Group:         "workers",
+		Consumer:      "a",
+		Count:         10,
+		Block:         50 * time.Millisecond,
+		MinIdle:       time.Minute,
+		ClaimInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var claimed []string
+	for _, msg := range receiveStream(t, c, len(ids)) {
+		claimed = append(claimed, msg.ID)
+	}
+	assert.ElementsMatch(t, ids, claimed)
+
+	pending, _ := r.Client().XPending(ctx, "jobs", "workers").Result()
+	assert.Equal(t, map[string]int64{"a": 25}, pending.Consumers)
+}
+
+func TestConsumerClaimWhileReplaying(t *testing.T) {
+	r, server := newTestRedis(t)
+	defer server.Close()
+	defer r.Close()
+
+	ctx := context.Background()
+	r.Client().XGroupCreateMkStream(ctx, "jobs", "workers", "0")
+
+	// pending for a (from a previous run) and dead, interleaved
+	var ids []string
+	for i, consumer := range []string{"a", "dead", "a"} {
+		id, _ := r.XAdd(ctx, "jobs", map[string]interface{}{"job": fmt.Sprint(i)}, 0)
+		r.Client().XReadGroup(ctx, &rds.XReadGroupArgs{Group: "workers", Consumer: consumer, Streams: []string{"jobs", ">"}, Count: 1})
+		ids = append(ids, id)
+	}
+
+	server.SetTime(time.Now().Add(time.Hour))
+
+	// claimed before the pending messages of a are read
+	c, err := r.Consume(ctx, ConsumerOptions{
+		Stream:        "jobs",
+		Group:         "workers",
+		Consumer:      "a",
+		Block:         50 * time.Millisecond,
+		MinIdle:       time.Minute,
+		ClaimInterval: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var received []string
+	for _, msg := range receiveStream(t, c, len(ids)) {
+		received = append(received, msg.ID)
+	}
+	assert.Equal(t, []string{ids[1], ids[0], ids[2]}, received)
+
+	select {
+	case msg := <-c.Messages():
+		t.Errorf("message %s delivered twice", msg.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNextStreamID(t *testing.T) {
+	next, ok := nextStreamID("1526985054069-0")
+	assert.True(t, ok)
+	assert.Equal(t, "1526985054069-1", next)
+
+	next, _ = nextStreamID("1526985054069-18446744073709551615")
+	assert.Equal(t, "1526985054070-0", next)
+
+	_, ok = nextStreamID("invalid")
+	assert.False(t, ok)
+}
@@ -0,0 +1,170 @@
+package datasource
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrDatabaseExists is returned when a name is registered twice
+var ErrDatabaseExists = errors.New("a database with the specified name is already registered")
+
+// DefaultRegistry is the registry used by RegisterDB, GetDB and CloseAll
+var DefaultRegistry = NewRegistry()
+
+// Registry holds several named databases (e.g. "main", "analytics"), each one
+// with its own configuration. Databases are opened on the first call to Get or
+// Access, and opened again after Close; Lookup and Names don't open them. It's
+// safe for concurrent use.
+type Registry struct {
+	mutex   sync.RWMutex
+	entries map[string]*registryEntry
+}
+
+type registryEntry struct {
+	mutex  sync.Mutex
+	config DBConfig
+	access *Generic
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]*registryEntry)}
+}
+
+// Register adds the configuration of a database. It's opened on the first call
+// to Get or Access.
+func (r *Registry) Register(name string, config DBConfig) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.entries[name]; exists {
+		return ErrDatabaseExists
+	}
+	r.entries[name] = &registryEntry{config: config}
+	return nil
+}
+
+// Get returns the database registered with the name, opening it if needed. It
+// returns ErrNoDatabase if the name is not registered.
+func (r *Registry) Get(name string) (*sqlx.DB, error) {
+	access, err := r.Access(name)
+	if err != nil {
+		return nil, err
+	}
+	return access.Get()
+}
+
+// Lookup returns the database registered with the name if it's open, without
+// opening it; e.g. to report the state of the databases in use.
+func (r *Registry) Lookup(name string) (*sqlx.DB, bool) {
+	r.mutex.RLock()
+	entry, exists := r.entries[name]
+	r.mutex.RUnlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+
+	if entry.access == nil {
+		return nil, false
+	}
+	db, err := entry.access.Get()
+	return db, err == nil
+}
+
+// Access returns the data access of the database registered with the name,
+// opening it if needed. If opening fails, nothing is left open (see
+// Generic.New), and it's retried on the next call.
+func (r *Registry) Access(name string) (*Generic, error) {
+	r.mutex.RLock()
+	entry, exists := r.entries[name]
+	r.mutex.RUnlock()
+
+	if !exists {
+		return nil, ErrNoDatabase
+	}
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+
+	if entry.access == nil {
+		access := new(Generic)
+		if _, err := access.New(entry.config); err != nil {
+			return nil, err
+		}
+		entry.access = access
+	}
+	return entry.access, nil
+}
+
+// Names returns the registered names, sorted
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close closes the database registered with the name, which is opened again on
+// the next call to Get. It returns ErrNoDatabase if the name is not registered.
+func (r *Registry) Close(name string) error {
+	r.mutex.RLock()
+	entry, exists := r.entries[name]
+	r.mutex.RUnlock()
+
+	if !exists {
+		return ErrNoDatabase
+	}
+	return entry.close()
+}
+
+// CloseAll closes all the open databases, returning the first error found. It
+// should be called when the server ends the execution.
+func (r *Registry) CloseAll() (err error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, entry := range r.entries {
+		if errClose := entry.close(); err == nil {
+			err = errClose
+		}
+	}
+	return
+}
+
+func (e *registryEntry) close() (err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.access != nil {
+		err = e.access.Close()
+		e.access = nil
+	}
+	return
+}
+
+// RegisterDB adds a database to the default registry
+func RegisterDB(name string, config DBConfig) error {
+	return DefaultRegistry.Register(name, config)
+}
+
+// GetDB returns a database of the default registry, opening it if needed
+func GetDB(name string) (*sqlx.DB, error) {
+	return DefaultRegistry.Get(name)
+}
+
+// CloseAll closes all the databases of the default registry
+func CloseAll() error {
+	return DefaultRegistry.CloseAll()
+}
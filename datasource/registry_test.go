@@ -0,0 +1,127 @@
+package datasource
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	r := NewRegistry()
+
+	assert.NoError(t, r.Register("main", DBConfig{Driver: "sqlite3", DBName: filepath.Join(dir, "main.db")}))
+	assert.NoError(t, r.Register("analytics", DBConfig{Driver: "sqlite3", DBName: filepath.Join(dir, "analytics.db")}))
+	assert.Equal(t, ErrDatabaseExists, r.Register("main", DBConfig{}))
+	assert.NoError(t, r.Register("broken", DBConfig{Driver: "oracle"}))
+	assert.Equal(t, []string{"analytics", "broken", "main"}, r.Names())
+
+	// opened once, even with concurrent calls
+	var wg sync.WaitGroup
+	dbs := make([]interface{}, 10)
+	for i := range dbs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			db, err := r.Get("main")
+			assert.NoError(t, err)
+			dbs[i] = db
+		}(i)
+	}
+	wg.Wait()
+	for _, db := range dbs {
+		assert.True(t, db == dbs[0])
+	}
+
+	analytics, err := r.Get("analytics")
+	if assert.NoError(t, err) {
+		assert.False(t, analytics == dbs[0])
+	}
+
+	_, err = r.Get("legacy")
+	assert.Equal(t, ErrNoDatabase, err)
+	_, err = r.Get("broken")
+	assert.Equal(t, ErrDriverNotSupported, err)
+
+	assert.NoError(t, r.CloseAll())
+
+	// opened again after closing
+	db, err := r.Get("main")
+	if assert.NoError(t, err) {
+		assert.NoError(t, db.Ping())
+	}
+	assert.NoError(t, r.CloseAll())
+}
+
+// countingDriver counts the open connections of the wrapped driver
+type countingDriver struct {
+	driver.Driver
+	open int32
+}
+
+func (cd *countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := cd.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&cd.open, 1)
+	return &countingConn{Conn: conn, driver: cd}, nil
+}
+
+type countingConn struct {
+	driver.Conn
+	driver *countingDriver
+}
+
+func (cc *countingConn) Close() error {
+	atomic.AddInt32(&cc.driver.open, -1)
+	return cc.Conn.Close()
+}
+
+var sqliteCounting = new(countingDriver)
+
+// registers an instrumentation that opens the primary with sqliteCounting and
+// fails for the replicas; configurations must have one replica
+func registerReplicaFailure() string {
+	var calls int32
+	RegisterInstrumentation("replica-failure", func(driverName string) (string, error) {
+		if atomic.AddInt32(&calls, 1)%2 == 0 {
+			return "", errors.New("replica failure")
+		}
+		return wrapDriver(driverName, "counting", func(d driver.Driver) driver.Driver {
+			sqliteCounting.Driver = d
+			return sqliteCounting
+		})
+	})
+	return "replica-failure"
+}
+
+func TestRegistryAccessReplicaFailure(t *testing.T) {
+	r := NewRegistry()
+	r.Register("main", DBConfig{
+		Driver:          "sqlite3",
+		DBName:          ":memory:",
+		Replicas:        []string{":memory:"},
+		Instrumentation: registerReplicaFailure(),
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := r.Access("main")
+		assert.EqualError(t, err, "replica failure")
+	}
+
+	// the primaries opened on each attempt were closed
+	assert.Equal(t, int32(0), atomic.LoadInt32(&sqliteCounting.open))
+}
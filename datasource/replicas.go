@@ -0,0 +1,225 @@
+package datasource
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tuckyapps/lit-go-tools/datasource/mysql"
+	"github.com/tuckyapps/lit-go-tools/datasource/postgresql"
+	"github.com/tuckyapps/lit-go-tools/datasource/sqlite3"
+)
+
+// Replica selection policies
+const (
+	ReplicaRoundRobin   = "round-robin"
+	ReplicaLeastLatency = "least-latency"
+)
+
+// default interval between the health checks of the replicas
+const defaultHealthCheckInterval = 10 * time.Second
+
+// ReplicaStatus is the state of a replica after the last health check
+type ReplicaStatus struct {
+	Address string
+	Healthy bool
+	Latency time.Duration // moving average of the health checks
+}
+
+type primaryKey struct{}
+
+// WithPrimary returns a context that makes ReaderContext return the primary,
+// e.g. to read data right after writing it.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+type replica struct {
+	address string
+	db      *sqlx.DB
+	healthy int32 // 1 if the last health check succeeded
+	latency int64 // nanoseconds
+}
+
+// replicaSet selects the replicas that passed the last health check. Replicas
+// are ejected when a check fails, and admitted again when a check succeeds.
+type replicaSet struct {
+	replicas []*replica
+	policy   string
+	interval time.Duration
+	next     uint32
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// opens the replicas of config and checks them, starting the periodic checks
+func newReplicaSet(config DBConfig) (*replicaSet, error) {
+	rs := &replicaSet{
+		policy:   config.ReplicaSelection,
+		interval: config.HealthCheckInterval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if rs.interval <= 0 {
+		rs.interval = defaultHealthCheckInterval
+	}
+
+	for _, address := range config.Replicas {
+		db, err := openReplica(config, address)
+		if db == nil {
+			rs.closeReplicas()
+			return nil, err
+		}
+
+		db.SetMaxOpenConns(config.MaxOpenConnections)
+		db.SetMaxIdleConns(config.MaxIdleConnections)
+		db.SetConnMaxLifetime(config.ConnectionLifetime)
+		rs.replicas = append(rs.replicas, &replica{address: address, db: db})
+	}
+
+	rs.check()
+	go rs.loop()
+
+	return rs, nil
+}
+
+// opens the replica without requiring it to be reachable, so it can be admitted
+// later. For sqlite3, the address is the database file.
+func openReplica(config DBConfig, address string) (*sqlx.DB, error) {
+	switch config.Driver {
+	case "postgres":
+		// the client pings the server, the error is ignored as the replica
+		// is checked later
+		options, err := postgresOptions(config, address)
+		if err != nil {
+			return nil, err
+		}
+
+		db, err := postgresql.Open(options)
+		if db == nil {
+			return nil, err
+		}
+		return db, nil
+	case "mysql":
+		options, err := mysqlOptions(config, address)
+		if err != nil {
+			return nil, err
+		}
+		return mysql.Open(options)
+	case "sqlite3":
+		driverName, err := sqlDriverName(config)
+		if err != nil {
+			return nil, err
+		}
+		return sqlite3.Open(driverName, address)
+	default:
+		return nil, ErrDriverNotSupported
+	}
+}
+
+func (rs *replicaSet) loop() {
+	defer close(rs.done)
+
+	ticker := time.NewTicker(rs.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rs.check()
+		case <-rs.stop:
+			return
+		}
+	}
+}
+
+// pings all the replicas concurrently, updating their state
+func (rs *replicaSet) check() {
+	var wg sync.WaitGroup
+
+	for _, r := range rs.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), rs.interval)
+			defer cancel()
+
+			start := time.Now()
+			if err := r.db.PingContext(ctx); err != nil {
+				atomic.StoreInt32(&r.healthy, 0)
+				return
+			}
+
+			latency := int64(time.Since(start))
+			if previous := atomic.LoadInt64(&r.latency); previous > 0 {
+				latency = (previous*4 + latency) / 5
+			}
+			atomic.StoreInt64(&r.latency, latency)
+			atomic.StoreInt32(&r.healthy, 1)
+		}(r)
+	}
+
+	wg.Wait()
+}
+
+// returns a healthy replica using the selection policy, or nil if there are none
+func (rs *replicaSet) pick() *sqlx.DB {
+	var healthy []*replica
+	for _, r := range rs.replicas {
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	if rs.policy == ReplicaLeastLatency {
+		best := healthy[0]
+		for _, r := range healthy[1:] {
+			if atomic.LoadInt64(&r.latency) < atomic.LoadInt64(&best.latency) {
+				best = r
+			}
+		}
+		return best.db
+	}
+
+	n := atomic.AddUint32(&rs.next, 1)
+	return healthy[int(n-1)%len(healthy)].db
+}
+
+func (rs *replicaSet) status() []ReplicaStatus {
+	status := make([]ReplicaStatus, len(rs.replicas))
+	for i, r := range rs.replicas {
+		status[i] = ReplicaStatus{
+			Address: r.address,
+			Healthy: atomic.LoadInt32(&r.healthy) == 1,
+			Latency: time.Duration(atomic.LoadInt64(&r.latency)),
+		}
+	}
+	return status
+}
+
+// stops the health checks and closes the replicas
+func (rs *replicaSet) close() error {
+	close(rs.stop)
+	<-rs.done
+	return rs.closeReplicas()
+}
+
+func (rs *replicaSet) closeReplicas() (err error) {
+	for _, r := range rs.replicas {
+		if errClose := r.db.Close(); err == nil {
+			err = errClose
+		}
+	}
+	return
+}
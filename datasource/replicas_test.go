@@ -0,0 +1,73 @@
+package datasource
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicaRouting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replicas")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	g := new(Generic)
+	_, err = g.New(DBConfig{
+		Driver:              "sqlite3",
+		DBName:              filepath.Join(dir, "primary.db"),
+		Replicas:            []string{filepath.Join(dir, "r1.db"), filepath.Join(dir, "r2.db")},
+		HealthCheckInterval: time.Hour,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer g.Close()
+
+	primary, _ := g.Writer()
+	r1, _ := g.Reader()
+	r2, _ := g.Reader()
+	r3, _ := g.Reader()
+	assert.False(t, r1 == primary)
+	assert.False(t, r1 == r2)
+	assert.True(t, r1 == r3)
+
+	reader, _ := g.ReaderContext(WithPrimary(context.Background()))
+	assert.True(t, reader == primary)
+
+	// ejected after a failed check
+	rs := g.replicas
+	rs.replicas[0].db.Close()
+	rs.check()
+	for i := 0; i < 3; i++ {
+		reader, _ = g.Reader()
+		assert.True(t, reader == rs.replicas[1].db)
+	}
+	assert.False(t, g.Replicas()[0].Healthy)
+
+	// admitted again after a successful check
+	atomic.StoreInt32(&rs.replicas[1].healthy, 0)
+	reader, _ = g.Reader()
+	assert.True(t, reader == primary)
+	rs.check()
+	reader, _ = g.Reader()
+	assert.True(t, reader == rs.replicas[1].db)
+}
+
+func TestReplicaLeastLatency(t *testing.T) {
+	rs := &replicaSet{policy: ReplicaLeastLatency}
+	for _, latency := range []time.Duration{30, 10, 20} {
+		rs.replicas = append(rs.replicas, &replica{db: new(sqlx.DB), healthy: 1, latency: int64(latency)})
+	}
+	rs.replicas[1].healthy = 0
+
+	assert.True(t, rs.pick() == rs.replicas[2].db)
+}
@@ -1,6 +1,8 @@
 package sqlite3
 
 import (
+	"database/sql"
+
 	"github.com/jmoiron/sqlx"
 	// import driver
 	_ "github.com/mattn/go-sqlite3"
@@ -8,8 +10,21 @@ import (
 
 // Init connects to the database server
 func Init(dbName string) (db *sqlx.DB, err error) {
-	db, err = sqlx.Open("sqlite3", dbName)
-	return
+	return Open("", dbName)
+}
+
+// Open connects to the database with the database/sql driver driverName, e.g.
+// one that instruments the SQLite driver. It's sqlite3 if empty.
+func Open(driverName, dbName string) (*sqlx.DB, error) {
+	if driverName == "" {
+		driverName = "sqlite3"
+	}
+
+	db, err := sql.Open(driverName, dbName)
+	if err != nil {
+		return nil, err
+	}
+	return sqlx.NewDb(db, "sqlite3"), nil
 }
 
 func RandFuncName() string {
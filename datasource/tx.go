@@ -0,0 +1,164 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrTxNotSupported is returned by WithTx when db isn't a *sqlx.DB or a *sqlx.Tx
+var ErrTxNotSupported = errors.New("transactions need a *sqlx.DB or a *sqlx.Tx")
+
+// Default transaction options
+const (
+	DefaultTxMaxRetries = 3
+	DefaultTxBackoff    = 50 * time.Millisecond
+)
+
+// TxOptions holds the configuration of the transactions run by WithTx
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+
+	// MaxRetries is the number of times the transaction is run again when it
+	// fails with a deadlock or a serialization error; 3 by default, and a
+	// negative value disables the retries
+	MaxRetries int
+
+	// Backoff is the wait before the first retry, doubled on each retry with
+	// some jitter; 50ms by default
+	Backoff time.Duration
+}
+
+// number of savepoints created, used to name them
+var savepoints uint64
+
+// WithTx runs fn in a transaction of db, committing it if fn returns nil, and
+// rolling it back if fn returns an error or panics. Deadlocks and serialization
+// errors (see IsRetryableError) are retried with backoff, running fn again, so
+// it shouldn't have side effects out of the database. opts can be nil.
+//
+// To nest transactions, pass the *sqlx.Tx as db: fn runs in a savepoint that
+// is rolled back on error, keeping the outer transaction. Nested calls aren't
+// retried, the error is returned so the outermost transaction is retried.
+//
+//	err := datasource.WithTx(ctx, db, nil, func(tx *sqlx.Tx) error {
+//		if _, err := tx.ExecContext(ctx, "UPDATE accounts SET ..."); err != nil {
+//			return err
+//		}
+//		return datasource.WithTx(ctx, tx, nil, func(tx *sqlx.Tx) error {
+//			...
+//		})
+//	})
+func WithTx(ctx context.Context, db sqlx.ExtContext, opts *TxOptions, fn func(tx *sqlx.Tx) error) error {
+	switch db := db.(type) {
+	case *sqlx.DB:
+		return runTx(ctx, db, opts, fn)
+	case *sqlx.Tx:
+		return runSavepoint(ctx, db, fn)
+	default:
+		return ErrTxNotSupported
+	}
+}
+
+func runTx(ctx context.Context, db *sqlx.DB, opts *TxOptions, fn func(tx *sqlx.Tx) error) error {
+	var options TxOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = DefaultTxMaxRetries
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = DefaultTxBackoff
+	}
+
+	backoff := options.Backoff
+	for retry := 0; ; retry++ {
+		err := runTxOnce(ctx, db, &sql.TxOptions{Isolation: options.Isolation, ReadOnly: options.ReadOnly}, fn)
+		if err == nil || retry >= options.MaxRetries || !IsRetryableError(err) {
+			return err
+		}
+
+		// waits backoff plus up to 50% more, so the transactions don't collide again
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+func runTxOnce(ctx context.Context, db *sqlx.DB, opts *sql.TxOptions, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func runSavepoint(ctx context.Context, tx *sqlx.Tx, fn func(tx *sqlx.Tx) error) (err error) {
+	name := fmt.Sprintf("sp_%d", atomic.AddUint64(&savepoints, 1))
+	if _, err = tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// IsRetryableError returns true if err is a deadlock or a serialization error,
+// so the transaction can succeed if it's run again: MySQL error 1213, PostgreSQL
+// errors 40001 and 40P01, and SQLite busy errors.
+func IsRetryableError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy
+	}
+
+	return false
+}
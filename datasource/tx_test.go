@@ -0,0 +1,153 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	sqlite "github.com/tuckyapps/lit-go-tools/datasource/sqlite3"
+)
+
+func newTxTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlite.Init(":memory:")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// each connection has its own in-memory database
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE items (name TEXT)")
+	assert.NoError(t, err)
+	return db
+}
+
+func itemNames(t *testing.T, db *sqlx.DB) []string {
+	var names []string
+	assert.NoError(t, db.Select(&names, "SELECT name FROM items ORDER BY name"))
+	return names
+}
+
+func insertItem(ctx context.Context, name string) func(tx *sqlx.Tx) error {
+	return func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO items (name) VALUES (?)", name)
+		return err
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	db := newTxTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	assert.NoError(t, WithTx(ctx, db, nil, insertItem(ctx, "a")))
+
+	errFailed := errors.New("failed")
+	err := WithTx(ctx, db, nil, func(tx *sqlx.Tx) error {
+		insertItem(ctx, "b")(tx)
+		return errFailed
+	})
+	assert.Equal(t, errFailed, err)
+
+	assert.Panics(t, func() {
+		WithTx(ctx, db, nil, func(tx *sqlx.Tx) error {
+			insertItem(ctx, "c")(tx)
+			panic("boom")
+		})
+	})
+
+	assert.Equal(t, []string{"a"}, itemNames(t, db))
+	assert.Equal(t, ErrTxNotSupported, WithTx(ctx, nil, nil, insertItem(ctx, "d")))
+}
+
+func TestWithTxNested(t *testing.T) {
+	db := newTxTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	err := WithTx(ctx, db, nil, func(tx *sqlx.Tx) error {
+		if err := insertItem(ctx, "outer")(tx); err != nil {
+			return err
+		}
+
+		// the failed savepoint is rolled back, keeping the outer changes
+		err := WithTx(ctx, tx, nil, func(tx *sqlx.Tx) error {
+			insertItem(ctx, "failed")(tx)
+			return errors.New("failed")
+		})
+		assert.Error(t, err)
+
+		assert.Panics(t, func() {
+			WithTx(ctx, tx, nil, func(tx *sqlx.Tx) error {
+				insertItem(ctx, "panicked")(tx)
+				panic("boom")
+			})
+		})
+
+		return WithTx(ctx, tx, nil, insertItem(ctx, "inner"))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"inner", "outer"}, itemNames(t, db))
+}
+
+func TestWithTxRetries(t *testing.T) {
+	db := newTxTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	busy := fmt.Errorf("insert: %w", sqlite3.Error{Code: sqlite3.ErrBusy})
+
+	calls := 0
+	err := WithTx(ctx, db, &TxOptions{Backoff: time.Millisecond}, func(tx *sqlx.Tx) error {
+		calls++
+		insertItem(ctx, fmt.Sprint("attempt ", calls))(tx)
+		if calls < 3 {
+			return busy
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []string{"attempt 3"}, itemNames(t, db))
+
+	calls = 0
+	err = WithTx(ctx, db, &TxOptions{MaxRetries: 2, Backoff: time.Millisecond}, func(tx *sqlx.Tx) error {
+		calls++
+		return busy
+	})
+	assert.Equal(t, busy, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = WithTx(ctx, db, &TxOptions{MaxRetries: -1}, func(tx *sqlx.Tx) error {
+		calls++
+		return busy
+	})
+	assert.Equal(t, busy, err)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = WithTx(ctx, db, nil, func(tx *sqlx.Tx) error {
+		calls++
+		return errors.New("not retryable")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	assert.True(t, IsRetryableError(&mysql.MySQLError{Number: 1213}))
+	assert.False(t, IsRetryableError(&mysql.MySQLError{Number: 1062}))
+	assert.True(t, IsRetryableError(&pq.Error{Code: "40001"}))
+	assert.True(t, IsRetryableError(fmt.Errorf("update: %w", &pq.Error{Code: "40P01"})))
+	assert.False(t, IsRetryableError(&pq.Error{Code: "23505"}))
+	assert.True(t, IsRetryableError(sqlite3.Error{Code: sqlite3.ErrBusy}))
+	assert.False(t, IsRetryableError(sqlite3.Error{Code: sqlite3.ErrConstraint}))
+	assert.False(t, IsRetryableError(errors.New("deadlock")))
+	assert.False(t, IsRetryableError(nil))
+}
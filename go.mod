@@ -3,10 +3,11 @@ module github.com/tuckyapps/lit-go-tools
 go 1.13
 
 require (
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/aws/aws-sdk-go v1.30.3
 	github.com/elazarl/goproxy v0.0.0-20200315184450-1f3cb6622dad // indirect
 	github.com/gin-gonic/gin v1.6.2
-	github.com/go-redis/redis v6.15.7+incompatible
+	github.com/go-redis/redis/v8 v8.11.4
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/gofrs/uuid v3.2.0+incompatible
 	github.com/jmoiron/sqlx v1.2.0
@@ -14,11 +15,10 @@ require (
 	github.com/lib/pq v1.2.0
 	github.com/mattn/go-sqlite3 v2.0.1+incompatible
 	github.com/newrelic/go-agent v2.16.1+incompatible
-	github.com/onsi/ginkgo v1.12.0 // indirect
-	github.com/onsi/gomega v1.9.0 // indirect
 	github.com/parnurzeal/gorequest v0.2.16
 	github.com/pquerna/ffjson v0.0.0-20190930134022-aa0246cd15f7
 	github.com/stretchr/testify v1.5.1
+	github.com/vmihailenco/msgpack/v4 v4.3.12
 	google.golang.org/appengine v1.6.5 // indirect
 	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
 	moul.io/http2curl v1.0.0 // indirect
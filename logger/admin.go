@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// levelChangeRequest is the body accepted by the level handler
+type levelChangeRequest struct {
+	Logger      string `json:"logger"`
+	Level       string `json:"level"`
+	RevertAfter string `json:"revert_after"`
+}
+
+// levelsResponse is the body returned by the level handler
+type levelsResponse struct {
+	Root    LevelStatus            `json:"root"`
+	Loggers map[string]LevelStatus `json:"loggers"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// LevelHandler returns an HTTP handler to view and change the log levels at
+// runtime. If registry is nil, the registry of the current logger is used.
+//
+//	GET    -> returns the root and named levels
+//	PUT    -> {"logger":"datasource","level":"debug","revert_after":"15m"}
+//	DELETE -> ?logger=datasource makes the named logger follow the root level again
+//
+// Empty logger means root. Parameters may also be sent in the query string. It
+// can be mounted in gin with gin.WrapH, or in Echo with echo.WrapHandler.
+func LevelHandler(registry *LevelRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lr := registry
+		if lr == nil {
+			lr = Levels()
+		}
+		if lr == nil {
+			writeLevels(w, http.StatusNotImplemented, nil, "current logger doesn't support runtime levels")
+			return
+		}
+
+		switch r.Method {
+
+		case http.MethodGet:
+			writeLevels(w, http.StatusOK, lr, "")
+
+		case http.MethodPut, http.MethodPost:
+			req := levelChangeRequest{
+				Logger:      r.URL.Query().Get("logger"),
+				Level:       r.URL.Query().Get("level"),
+				RevertAfter: r.URL.Query().Get("revert_after"),
+			}
+			if r.Body != nil && r.ContentLength != 0 {
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					writeLevels(w, http.StatusBadRequest, lr, "invalid request body")
+					return
+				}
+			}
+
+			level, err := ParseLevel(req.Level)
+			if err != nil {
+				writeLevels(w, http.StatusBadRequest, lr, err.Error())
+				return
+			}
+
+			var revertAfter time.Duration
+			if req.RevertAfter != "" {
+				if revertAfter, err = time.ParseDuration(req.RevertAfter); err != nil || revertAfter < 0 {
+					writeLevels(w, http.StatusBadRequest, lr, "invalid revert_after duration")
+					return
+				}
+			}
+
+			lr.Set(req.Logger, level, revertAfter)
+			writeLevels(w, http.StatusOK, lr, "")
+
+		case http.MethodDelete:
+			lr.Reset(r.URL.Query().Get("logger"))
+			writeLevels(w, http.StatusOK, lr, "")
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeLevels(w http.ResponseWriter, status int, lr *LevelRegistry, errMsg string) {
+	resp := levelsResponse{Loggers: make(map[string]LevelStatus), Error: errMsg}
+
+	if lr != nil {
+		for name, st := range lr.Status() {
+			if name == "" {
+				resp.Root = st
+			} else {
+				resp.Loggers[name] = st
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
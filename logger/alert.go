@@ -0,0 +1,270 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tuckyapps/lit-go-tools/core/email"
+)
+
+// Alert severities
+const (
+	SeverityInfo = iota
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+var severityNames = map[int]string{
+	SeverityInfo:     "info",
+	SeverityWarning:  "warning",
+	SeverityError:    "error",
+	SeverityCritical: "critical",
+}
+
+// Alert sink types, used in AlertSinkConfig
+const (
+	SinkSlack     = "slack"
+	SinkTeams     = "teams"
+	SinkWebhook   = "webhook"
+	SinkEmail     = "email"
+	SinkPagerDuty = "pagerduty"
+
+	// SinkSES is registered by importing the alertses package, so the AWS SDK
+	// is only linked when it's used
+	SinkSES = "ses"
+)
+
+// Alerting errors
+var (
+	ErrUnknownSink     = errors.New("unknown alert sink")
+	ErrInvalidSinkType = errors.New("invalid alert sink type")
+)
+
+// Alert is a notification sent to an alert sink
+type Alert struct {
+	Title    string            `json:"title"`
+	Text     string            `json:"text"`
+	Severity int               `json:"-"`
+	Source   string            `json:"source,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	Time     time.Time         `json:"time"`
+}
+
+// SeverityName returns the name of the alert severity
+func (a Alert) SeverityName() string {
+	return SeverityName(a.Severity)
+}
+
+// HasTag returns true if the alert has the indicated tag
+func (a Alert) HasTag(tag string) bool {
+	for _, t := range a.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Alerter is implemented by the alert sinks (Slack, Teams, email...)
+type Alerter interface {
+	Send(ctx context.Context, alert Alert) error
+}
+
+// IdentifiedAlerter is implemented by the sinks that can identify their
+// destination (e.g. the webhook URL), so AlertDispatcher groups the identical
+// alerts sent through different instances of the same sink
+type IdentifiedAlerter interface {
+	Alerter
+	ID() string
+}
+
+// SeverityName returns the name of the indicated severity
+func SeverityName(severity int) string {
+	if name, exists := severityNames[severity]; exists {
+		return name
+	}
+	return severityNames[SeverityCritical]
+}
+
+// ParseSeverity converts a severity name (info, warning, error, critical) to its value
+func ParseSeverity(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for severity, name := range severityNames {
+		if name == s {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid alert severity '%s'", s)
+}
+
+// AlertRoute sends the alerts with, at least, MinSeverity to the indicated sinks.
+// If Tags is not empty, alerts must have any of them.
+type AlertRoute struct {
+	MinSeverity string   `json:"min_severity"`
+	Tags        []string `json:"tags,omitempty"`
+	Sinks       []string `json:"sinks"`
+}
+
+// matches returns true if the alert should be sent through the route
+func (r AlertRoute) matches(alert Alert) bool {
+	if r.MinSeverity != "" {
+		if minSeverity, err := ParseSeverity(r.MinSeverity); err != nil || alert.Severity < minSeverity {
+			return false
+		}
+	}
+
+	if len(r.Tags) == 0 {
+		return true
+	}
+	for _, tag := range r.Tags {
+		if alert.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// AlertRouter is an Alerter that sends each alert to all the sinks of the
+// routes it matches. Each sink receives the alert once, even if it's included
+// in several routes.
+type AlertRouter struct {
+	sinks  map[string]Alerter
+	routes []AlertRoute
+}
+
+// NewAlertRouter creates a router using the named sinks and the routes
+func NewAlertRouter(sinks map[string]Alerter, routes []AlertRoute) (*AlertRouter, error) {
+	for _, r := range routes {
+		if r.MinSeverity != "" {
+			if _, err := ParseSeverity(r.MinSeverity); err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range r.Sinks {
+			if _, exists := sinks[name]; !exists {
+				return nil, fmt.Errorf("%w: '%s'", ErrUnknownSink, name)
+			}
+		}
+	}
+
+	return &AlertRouter{sinks: sinks, routes: routes}, nil
+}
+
+// Send sends the alert to the sinks of the matching routes. All the sinks are
+// tried; the returned error includes the ones that failed.
+func (ar *AlertRouter) Send(ctx context.Context, alert Alert) error {
+	var failed []string
+	sent := make(map[string]bool)
+
+	for _, r := range ar.routes {
+		if !r.matches(alert) {
+			continue
+		}
+
+		for _, name := range r.Sinks {
+			if sent[name] {
+				continue
+			}
+			sent[name] = true
+
+			if err := ar.sinks[name].Send(ctx, alert); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %s", name, err))
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("error sending alert to %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// AlertSinkConfig holds the configuration of an alert sink. Fields used depend on the type:
+//
+//	slack     -> URL (webhook)
+//	teams     -> URL (webhook)
+//	webhook   -> URL, Headers
+//	email     -> From, To (sent through the SMTP server)
+//	ses       -> From, To (sent through AWS SES, see alertses)
+//	pagerduty -> RoutingKey, URL (optional, Events API v2 by default)
+type AlertSinkConfig struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	URL        string            `json:"url,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	From       string            `json:"from,omitempty"`
+	To         []string          `json:"to,omitempty"`
+	RoutingKey string            `json:"routing_key,omitempty"`
+}
+
+// AlertRouterConfig holds the sinks and routes used to build an AlertRouter, so
+// alert destinations can be changed through the service settings.
+type AlertRouterConfig struct {
+	Sinks  []AlertSinkConfig `json:"sinks"`
+	Routes []AlertRoute      `json:"routes"`
+}
+
+// SinkFactory creates a sink of a type registered with RegisterSinkType
+type SinkFactory func(config AlertSinkConfig, settings LogSettings) (Alerter, error)
+
+var (
+	sinkTypesMutex sync.RWMutex
+	sinkTypes      = make(map[string]SinkFactory)
+)
+
+// RegisterSinkType makes a sink type available in AlertSinkConfig, replacing
+// any previous one. The built-in types can't be replaced.
+func RegisterSinkType(sinkType string, factory SinkFactory) {
+	sinkTypesMutex.Lock()
+	defer sinkTypesMutex.Unlock()
+	sinkTypes[sinkType] = factory
+}
+
+// NewAlertRouterFromConfig creates the configured sinks and an AlertRouter using
+// them. Settings provide the HTTP client and app name; smtp is only required
+// for email sinks.
+func NewAlertRouterFromConfig(config AlertRouterConfig, settings LogSettings, smtp *email.SMTPCredentials) (*AlertRouter, error) {
+	sinks := make(map[string]Alerter, len(config.Sinks))
+
+	for _, sc := range config.Sinks {
+		var sink Alerter
+
+		switch sc.Type {
+		case SinkSlack:
+			sink = NewSlackAlerter(sc.URL, settings)
+		case SinkTeams:
+			sink = &TeamsAlerter{WebHook: sc.URL, Client: settings.GetHTTPClient()}
+		case SinkWebhook:
+			sink = &WebhookAlerter{URL: sc.URL, Headers: sc.Headers, Client: settings.GetHTTPClient()}
+		case SinkEmail:
+			if smtp == nil {
+				return nil, fmt.Errorf("missing SMTP credentials for sink '%s'", sc.Name)
+			}
+			sink = NewSMTPAlerter(smtp, sc.From, sc.To)
+		case SinkPagerDuty:
+			sink = &PagerDutyAlerter{RoutingKey: sc.RoutingKey, URL: sc.URL, Client: settings.GetHTTPClient()}
+		default:
+			sinkTypesMutex.RLock()
+			factory, found := sinkTypes[sc.Type]
+			sinkTypesMutex.RUnlock()
+			if !found {
+				return nil, fmt.Errorf("%w: '%s'", ErrInvalidSinkType, sc.Type)
+			}
+
+			var err error
+			if sink, err = factory(sc, settings); err != nil {
+				return nil, fmt.Errorf("error creating sink '%s': %w", sc.Name, err)
+			}
+		}
+
+		sinks[sc.Name] = sink
+	}
+
+	return NewAlertRouter(sinks, config.Routes)
+}
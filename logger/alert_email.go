@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tuckyapps/lit-go-tools/core/email"
+)
+
+// EmailAlerter sends the alerts by email, as plain text
+type EmailAlerter struct {
+	From string
+	To   []string
+	send func(subject, text string) error
+}
+
+// NewEmailAlerter creates an EmailAlerter that sends the emails through send,
+// e.g. to use another email service (see alertses)
+func NewEmailAlerter(from string, to []string, send func(subject, text string) error) *EmailAlerter {
+	return &EmailAlerter{From: from, To: to, send: send}
+}
+
+// NewSMTPAlerter creates an EmailAlerter that sends the emails through the SMTP
+// server (see core/email)
+func NewSMTPAlerter(credentials *email.SMTPCredentials, from string, to []string) *EmailAlerter {
+	ea := &EmailAlerter{From: from, To: to}
+	ea.send = func(subject, text string) error {
+		return email.SendEmail(&email.Email{
+			FromAddress: ea.From,
+			To:          ea.To,
+			Subject:     subject,
+			Text:        text,
+		}, credentials)
+	}
+	return ea
+}
+
+// Send sends the alert by email. The context is only checked before sending,
+// since email clients don't support cancellation.
+func (ea *EmailAlerter) Send(ctx context.Context, alert Alert) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("[%s] %s", strings.ToUpper(alert.SeverityName()), alert.Title)
+	if alert.Source != "" {
+		subject = fmt.Sprintf("[%s]%s", alert.Source, subject)
+	}
+
+	body := new(strings.Builder)
+	body.WriteString(alert.Text)
+	body.WriteString("\n")
+	for _, k := range sortedKeys(alert.Fields) {
+		fmt.Fprintf(body, "\n%s: %s", k, alert.Fields[k])
+	}
+	if len(alert.Tags) > 0 {
+		fmt.Fprintf(body, "\ntags: %s", strings.Join(alert.Tags, ", "))
+	}
+
+	return ea.send(subject, body.String())
+}
+
+// ID identifies the destination, the sender and recipients
+func (ea *EmailAlerter) ID() string {
+	return SinkEmail + ":" + ea.From + ":" + strings.Join(ea.To, ",")
+}
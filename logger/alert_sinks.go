@@ -0,0 +1,312 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// default endpoint of PagerDuty's Events API v2
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// colors used for each severity in Slack and Teams
+var (
+	slackColors = map[int]string{
+		SeverityInfo:     ColorGood,
+		SeverityWarning:  ColorWarning,
+		SeverityError:    ColorDanger,
+		SeverityCritical: ColorDanger,
+	}
+	teamsColors = map[int]string{
+		SeverityInfo:     "2EB886",
+		SeverityWarning:  "DAA038",
+		SeverityError:    "A30200",
+		SeverityCritical: "A30200",
+	}
+)
+
+// SlackAlerter sends the alerts to a Slack webhook
+type SlackAlerter struct {
+	WebHook  string
+	Settings LogSettings
+}
+
+// NewSlackAlerter creates an Alerter for the Slack webhook; settings provide the
+// HTTP client and the app name used as username.
+func NewSlackAlerter(webHook string, settings LogSettings) *SlackAlerter {
+	return &SlackAlerter{WebHook: webHook, Settings: settings}
+}
+
+// Send sends the alert to Slack, as an attachment with the alert fields
+func (sa *SlackAlerter) Send(ctx context.Context, alert Alert) error {
+	text := alert.Text
+	if alert.Severity >= SeverityError && text != "" {
+		text = SlackCodeBlock(text)
+	}
+
+	attachment := SlackAttachment{
+		Fallback: alert.Title,
+		Title:    alert.Title,
+		Color:    slackColors[alert.Severity],
+		Text:     text,
+		MrkdwnIn: []string{"text"},
+	}
+	for _, k := range sortedKeys(alert.Fields) {
+		attachment.Fields = append(attachment.Fields, SlackField{Title: k, Value: alert.Fields[k], Short: true})
+	}
+	if len(alert.Tags) > 0 {
+		attachment.Footer = strings.Join(alert.Tags, ", ")
+	}
+	if !alert.Time.IsZero() {
+		attachment.Timestamp = alert.Time.Unix()
+	}
+
+	msg := &SlackMessage{Username: alert.Source, Attachments: []SlackAttachment{attachment}}
+	return SendSlackMessageContext(ctx, sa.WebHook, msg, sa.Settings)
+}
+
+// ID identifies the destination, the webhook
+func (sa *SlackAlerter) ID() string {
+	return SinkSlack + ":" + sa.WebHook
+}
+
+// TeamsAlerter sends the alerts to a Microsoft Teams incoming webhook
+type TeamsAlerter struct {
+	WebHook string
+	Client  *http.Client
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type teamsSection struct {
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsMessageCard struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	ThemeColor string         `json:"themeColor,omitempty"`
+	Summary    string         `json:"summary"`
+	Title      string         `json:"title"`
+	Text       string         `json:"text"`
+	Sections   []teamsSection `json:"sections,omitempty"`
+}
+
+// Send sends the alert to Teams as a message card
+func (ta *TeamsAlerter) Send(ctx context.Context, alert Alert) error {
+	card := teamsMessageCard{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		ThemeColor: teamsColors[alert.Severity],
+		Summary:    alert.Title,
+		Title:      alert.Title,
+		Text:       alert.Text,
+	}
+
+	facts := []teamsFact{{Name: "Severity", Value: alert.SeverityName()}}
+	if alert.Source != "" {
+		facts = append(facts, teamsFact{Name: "Source", Value: alert.Source})
+	}
+	for _, k := range sortedKeys(alert.Fields) {
+		facts = append(facts, teamsFact{Name: k, Value: alert.Fields[k]})
+	}
+	card.Sections = []teamsSection{{Facts: facts}}
+
+	return postJSON(ctx, ta.Client, "Teams", ta.WebHook, card, nil)
+}
+
+// ID identifies the destination, the webhook
+func (ta *TeamsAlerter) ID() string {
+	return SinkTeams + ":" + ta.WebHook
+}
+
+// WebhookAlerter sends the alerts as JSON to a generic HTTP endpoint
+type WebhookAlerter struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// webhookPayload is the body sent by WebhookAlerter
+type webhookPayload struct {
+	Alert
+	Severity string `json:"severity"`
+}
+
+// Send posts the alert to the configured URL
+func (wa *WebhookAlerter) Send(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, wa.Client, "Webhook", wa.URL, webhookPayload{Alert: alert, Severity: alert.SeverityName()}, wa.Headers)
+}
+
+// ID identifies the destination, the URL
+func (wa *WebhookAlerter) ID() string {
+	return SinkWebhook + ":" + wa.URL
+}
+
+// PagerDutyAlerter triggers events through PagerDuty's Events API v2
+type PagerDutyAlerter struct {
+	RoutingKey string
+	URL        string // Events API v2 by default
+	Client     *http.Client
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key,omitempty"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+// Send triggers a PagerDuty event for the alert; alerts with the same source
+// and title are grouped in the same incident.
+func (pa *PagerDutyAlerter) Send(ctx context.Context, alert Alert) error {
+	endpoint := pa.URL
+	if endpoint == "" {
+		endpoint = pagerDutyEventsURL
+	}
+
+	source := alert.Source
+	if source == "" {
+		source = "unknown"
+	}
+
+	details := make(map[string]string, len(alert.Fields)+1)
+	for k, v := range alert.Fields {
+		details[k] = v
+	}
+	if alert.Text != "" {
+		details["text"] = alert.Text
+	}
+
+	event := pagerDutyEvent{
+		RoutingKey:  pa.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    source + ":" + alert.Title,
+		Payload: pagerDutyPayload{
+			Summary:       alert.Title,
+			Source:        source,
+			Severity:      alert.SeverityName(),
+			CustomDetails: details,
+		},
+	}
+	if !alert.Time.IsZero() {
+		event.Payload.Timestamp = alert.Time.Format(time.RFC3339)
+	}
+	if len(alert.Tags) > 0 {
+		event.Payload.Class = alert.Tags[0]
+	}
+
+	return postJSON(ctx, pa.Client, "PagerDuty", endpoint, event, nil)
+}
+
+// ID identifies the destination, the routing key and URL
+func (pa *PagerDutyAlerter) ID() string {
+	return SinkPagerDuty + ":" + pa.RoutingKey + ":" + pa.URL
+}
+
+// posts payload encoded as JSON, returning an error for non 2xx responses.
+// The errors name the service instead of the URL, which usually holds a secret.
+func postJSON(ctx context.Context, client *http.Client, service, endpoint string, payload interface{}, headers map[string]string) error {
+	if endpoint == "" {
+		return ErrInvalidChannel
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Error creating request to %s: %w", service, withoutURL(err))
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error sending alert to %s: %w", service, withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return newHTTPError(service, resp)
+	}
+	return nil
+}
+
+// returns the cause of a *url.Error, whose message includes the URL
+func withoutURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
+
+// HTTPError is returned by the alert sinks when the service responds with an
+// error status
+type HTTPError struct {
+	Service    string
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // from the Retry-After header, if present
+}
+
+func (he *HTTPError) Error() string {
+	return fmt.Sprintf("%s returned status %s", he.Service, he.Status)
+}
+
+// Temporary returns true if the request may succeed if retried
+func (he *HTTPError) Temporary() bool {
+	return he.StatusCode == http.StatusTooManyRequests || he.StatusCode >= http.StatusInternalServerError
+}
+
+func newHTTPError(service string, resp *http.Response) *HTTPError {
+	he := &HTTPError{Service: service, StatusCode: resp.StatusCode, Status: resp.Status}
+
+	// Retry-After may be a number of seconds or a date
+	if ra := resp.Header.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil && secs > 0 {
+			he.RetryAfter = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(ra); err == nil {
+			he.RetryAfter = time.Until(t)
+		}
+	}
+
+	return he
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
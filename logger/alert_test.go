@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingAlerter struct {
+	alerts []Alert
+	err    error
+}
+
+func (ca *countingAlerter) Send(ctx context.Context, alert Alert) error {
+	ca.alerts = append(ca.alerts, alert)
+	return ca.err
+}
+
+func TestAlertRouter(t *testing.T) {
+	chat := new(countingAlerter)
+	pager := new(countingAlerter)
+	payments := new(countingAlerter)
+
+	router, err := NewAlertRouter(
+		map[string]Alerter{"chat": chat, "pager": pager, "payments": payments},
+		[]AlertRoute{
+			{MinSeverity: "warning", Sinks: []string{"chat"}},
+			{MinSeverity: "critical", Sinks: []string{"pager", "chat"}},
+			{Tags: []string{"payments"}, Sinks: []string{"payments"}},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, router.Send(ctx, Alert{Title: "info", Severity: SeverityInfo}))
+	assert.NoError(t, router.Send(ctx, Alert{Title: "error", Severity: SeverityError}))
+	assert.NoError(t, router.Send(ctx, Alert{Title: "down", Severity: SeverityCritical}))
+	assert.NoError(t, router.Send(ctx, Alert{Title: "refund", Severity: SeverityInfo, Tags: []string{"payments"}}))
+
+	assert.Len(t, chat.alerts, 2)
+	assert.Len(t, pager.alerts, 1)
+	assert.Len(t, payments.alerts, 1)
+
+	// failing sinks are reported, but the rest still receive the alert
+	pager.err = errors.New("unavailable")
+	err = router.Send(ctx, Alert{Title: "down again", Severity: SeverityCritical})
+	assert.Error(t, err)
+	assert.Len(t, chat.alerts, 3)
+
+	// invalid configurations
+	_, err = NewAlertRouter(map[string]Alerter{}, []AlertRoute{{Sinks: []string{"nope"}}})
+	assert.True(t, errors.Is(err, ErrUnknownSink))
+	_, err = NewAlertRouter(map[string]Alerter{"chat": chat}, []AlertRoute{{MinSeverity: "meh", Sinks: []string{"chat"}}})
+	assert.Error(t, err)
+}
+
+func TestAlertRouterFromConfigSinkTypes(t *testing.T) {
+	config := AlertRouterConfig{
+		Sinks:  []AlertSinkConfig{{Name: "mail", Type: SinkSES, From: "alerts@example.com"}},
+		Routes: []AlertRoute{{Sinks: []string{"mail"}}},
+	}
+
+	// not registered unless alertses is imported
+	_, err := NewAlertRouterFromConfig(config, testSettings{}, nil)
+	assert.True(t, errors.Is(err, ErrInvalidSinkType))
+
+	custom := new(countingAlerter)
+	RegisterSinkType(SinkSES, func(AlertSinkConfig, LogSettings) (Alerter, error) { return custom, nil })
+	defer delete(sinkTypes, SinkSES)
+
+	router, err := NewAlertRouterFromConfig(config, testSettings{}, nil)
+	if assert.NoError(t, err) {
+		assert.NoError(t, router.Send(context.Background(), Alert{Title: "down"}))
+		assert.Len(t, custom.alerts, 1)
+	}
+}
+
+func TestWebhookAlerter(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	wa := &WebhookAlerter{URL: server.URL, Headers: map[string]string{"X-Token": "secret"}}
+	err := wa.Send(context.Background(), Alert{Title: "disk \"full\"", Severity: SeverityWarning, Tags: []string{"infra"}})
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "disk \"full\"", received["title"])
+		assert.Equal(t, "warning", received["severity"])
+	}
+}
+
+func TestWebhookAlerterErrorHidesURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	secretURL := server.URL + "/hooks/T000/B000/secret-token"
+	wa := &WebhookAlerter{URL: secretURL}
+	err := wa.Send(context.Background(), Alert{Title: "forbidden"})
+
+	var httpErr *HTTPError
+	if assert.True(t, errors.As(err, &httpErr)) {
+		assert.Equal(t, "Webhook", httpErr.Service)
+		assert.Equal(t, http.StatusForbidden, httpErr.StatusCode)
+	}
+	assert.NotContains(t, err.Error(), "secret-token")
+
+	// transport errors don't include the URL either
+	server.Close()
+	err = wa.Send(context.Background(), Alert{Title: "unreachable"})
+	if assert.Error(t, err) {
+		assert.NotContains(t, err.Error(), "secret-token")
+	}
+}
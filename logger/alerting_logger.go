@@ -0,0 +1,261 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// max time spent sending the alert of a Fatal call, before exiting
+const fatalAlertTimeout = 5 * time.Second
+
+// AlertingConfig holds the options used to create an AlertingLogger
+type AlertingConfig struct {
+	// Sink is where the alerts are sent (e.g. a SlackAlerter or an AlertRouter)
+	Sink Alerter
+
+	// Threshold is the less severe level forwarded: LevelError (default) forwards
+	// Error and Fatal calls, LevelWarn forwards Warn calls too.
+	Threshold int
+
+	// RateLimit is the time during which the same message is forwarded only once
+	// (1 minute by default)
+	RateLimit time.Duration
+
+	// Source is the name of the service, included in the alerts
+	Source string
+
+	// Tags are included in all the alerts, so they can be routed
+	Tags []string
+
+	// Dispatcher sends the alerts in background; if nil, a new one is created
+	// using the default configuration
+	Dispatcher *AlertDispatcher
+}
+
+// AlertingLogger is a Logger that forwards the entries above a threshold to an
+// alert sink, besides logging them with the wrapped logger. It allows existing
+// GetLogger().Errorf calls to report errors without changes:
+//
+//	logger.SetLogger(logger.NewAlertingLogger(logger.GetLogger(), logger.AlertingConfig{
+//		Sink:   logger.NewSlackAlerter(webHook, settings),
+//		Source: "auth-service",
+//	}))
+type AlertingLogger struct {
+	Logger
+	config  AlertingConfig
+	fields  map[string]interface{}
+	limiter *messageLimiter
+}
+
+// NewAlertingLogger wraps l so the entries above the threshold are also sent
+// to the configured sink.
+func NewAlertingLogger(l Logger, config AlertingConfig) *AlertingLogger {
+	if config.RateLimit <= 0 {
+		config.RateLimit = time.Minute
+	}
+	if config.Dispatcher == nil {
+		dc := DefaultDispatcherConfig()
+		dc.OnError = func(alert Alert, err error) {
+			// use the wrapped logger, so failures are not forwarded again
+			l.Errorf("Found an error sending alert '%s': %s", alert.Title, err)
+		}
+		config.Dispatcher = NewAlertDispatcher(dc)
+	}
+
+	return &AlertingLogger{
+		Logger:  l,
+		config:  config,
+		limiter: &messageLimiter{period: config.RateLimit, sent: make(map[string]time.Time)},
+	}
+}
+
+// Warn prints the arguments to the warning logger, forwarding them if the
+// threshold includes warnings.
+func (al *AlertingLogger) Warn(v ...interface{}) {
+	al.Logger.Warn(v...)
+	al.forward(LevelWarn, fmt.Sprint(v...))
+}
+
+// Warnf is like Warn, but arguments are handled like in fmt.Printf.
+func (al *AlertingLogger) Warnf(format string, v ...interface{}) {
+	al.Logger.Warnf(format, v...)
+	al.forward(LevelWarn, fmt.Sprintf(format, v...))
+}
+
+// Error prints the arguments to the error logger and forwards them.
+func (al *AlertingLogger) Error(v ...interface{}) {
+	al.Logger.Error(v...)
+	al.forward(LevelError, fmt.Sprint(v...))
+}
+
+// Errorf is like Error, but arguments are handled like in fmt.Printf.
+func (al *AlertingLogger) Errorf(format string, v ...interface{}) {
+	al.Logger.Errorf(format, v...)
+	al.forward(LevelError, fmt.Sprintf(format, v...))
+}
+
+// Fatal sends a critical alert, including the stack trace, and then calls the
+// wrapped logger's Fatal.
+func (al *AlertingLogger) Fatal(v ...interface{}) {
+	al.fatal(fmt.Sprint(v...))
+	al.Logger.Fatal(v...)
+}
+
+// Fatalf is like Fatal, but arguments are handled like in fmt.Printf.
+func (al *AlertingLogger) Fatalf(format string, v ...interface{}) {
+	al.fatal(fmt.Sprintf(format, v...))
+	al.Logger.Fatalf(format, v...)
+}
+
+// WithFields returns a child of the wrapped logger that also forwards its
+// entries; fields are included in the alerts.
+func (al *AlertingLogger) WithFields(fields map[string]interface{}) Logger {
+	child := *al
+	child.Logger = al.Logger.WithFields(fields)
+	child.fields = make(map[string]interface{}, len(al.fields)+len(fields))
+	for k, v := range al.fields {
+		child.fields[k] = v
+	}
+	for k, v := range fields {
+		child.fields[k] = v
+	}
+	return &child
+}
+
+// With returns a child logger including the field key=value.
+func (al *AlertingLogger) With(key string, value interface{}) Logger {
+	return al.WithFields(map[string]interface{}{key: value})
+}
+
+// Named returns a named child, if the wrapped logger supports it.
+func (al *AlertingLogger) Named(name string) Logger {
+	nl, ok := al.Logger.(NamedLogger)
+	if !ok {
+		return al
+	}
+
+	child := al.With("logger", name).(*AlertingLogger)
+	child.Logger = nl.Named(name)
+	return child
+}
+
+// Levels returns the level registry of the wrapped logger, or nil if it doesn't
+// support named loggers.
+func (al *AlertingLogger) Levels() *LevelRegistry {
+	if nl, ok := al.Logger.(NamedLogger); ok {
+		return nl.Levels()
+	}
+	return nil
+}
+
+// Close sends the pending alerts and closes the wrapped logger, if it supports it.
+func (al *AlertingLogger) Close(ctx context.Context) error {
+	err := al.config.Dispatcher.Close(ctx)
+	if c, ok := al.Logger.(interface{ Close(context.Context) error }); ok {
+		if errClose := c.Close(ctx); err == nil {
+			err = errClose
+		}
+	}
+	return err
+}
+
+// sends the alert for an entry, if its level is within the threshold and the
+// same message wasn't sent recently. It must be called directly from the
+// logging methods, so the caller is found.
+func (al *AlertingLogger) forward(level int, msg string) {
+	if !Enabled(al.config.Threshold, level) || !al.limiter.allow(fmt.Sprintf("%d:%s", level, msg)) {
+		return
+	}
+
+	alert := al.buildAlert(level, msg, caller(3))
+	al.config.Dispatcher.Dispatch(al.config.Sink, alert)
+}
+
+// sends the critical alert synchronously, since the process is about to exit
+func (al *AlertingLogger) fatal(msg string) {
+	alert := al.buildAlert(LevelError, msg, caller(3))
+	alert.Severity = SeverityCritical
+	alert.Text = fmt.Sprintf("%s\n\n%s", msg, debug.Stack())
+
+	ctx, cancel := context.WithTimeout(context.Background(), fatalAlertTimeout)
+	defer cancel()
+
+	// queued alerts first, they may explain the failure
+	al.config.Dispatcher.Close(ctx)
+	if err := al.config.Sink.Send(ctx, alert); err != nil {
+		al.Logger.Errorf("Found an error sending alert '%s': %s", alert.Title, err)
+	}
+}
+
+func (al *AlertingLogger) buildAlert(level int, msg, location string) Alert {
+	severity := SeverityError
+	if level == LevelWarn {
+		severity = SeverityWarning
+	}
+
+	alert := Alert{
+		Title:    msg,
+		Text:     msg,
+		Severity: severity,
+		Source:   al.config.Source,
+		Tags:     al.config.Tags,
+		Fields:   make(map[string]string, len(al.fields)+1),
+		Time:     time.Now(),
+	}
+
+	// long messages are shown only in the text
+	if len(alert.Title) > 100 {
+		alert.Title = alert.Title[:97] + "..."
+	}
+
+	for k, v := range al.fields {
+		alert.Fields[k] = fmt.Sprint(v)
+	}
+	if location != "" {
+		alert.Fields["caller"] = location
+	}
+
+	return alert
+}
+
+// returns the file:line of the function skip levels up in the stack
+func caller(skip int) string {
+	if _, file, line, ok := runtime.Caller(skip); ok {
+		return fmt.Sprintf("%s:%d", filepath.Base(filepath.Dir(file))+"/"+filepath.Base(file), line)
+	}
+	return ""
+}
+
+// messageLimiter allows each message once per period
+type messageLimiter struct {
+	period time.Duration
+	mutex  sync.Mutex
+	sent   map[string]time.Time
+}
+
+func (ml *messageLimiter) allow(key string) bool {
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+
+	now := time.Now()
+	if sentAt, exists := ml.sent[key]; exists && now.Sub(sentAt) < ml.period {
+		return false
+	}
+
+	// forget the expired messages from time to time, so the map doesn't grow forever
+	if len(ml.sent) >= 1000 {
+		for k, sentAt := range ml.sent {
+			if now.Sub(sentAt) >= ml.period {
+				delete(ml.sent, k)
+			}
+		}
+	}
+
+	ml.sent[key] = now
+	return true
+}
@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertingLogger(t *testing.T) {
+	sink := new(syncAlerter)
+	output := new(strings.Builder)
+	inner := NewSimpleLoggerWithConfig(Config{Level: LevelDebug, Output: output})
+
+	al := NewAlertingLogger(inner, AlertingConfig{
+		Sink:       sink,
+		Source:     "auth-service",
+		Dispatcher: NewAlertDispatcher(DispatcherConfig{GroupWindow: -1}),
+	})
+
+	al.Info("not forwarded")
+	al.Warnf("below %s", "threshold")
+	al.Errorf("connection refused: %s", "db")
+	al.Errorf("connection refused: %s", "db") // rate limited
+	al.With("user", 42).Error("invalid token")
+	assert.NoError(t, al.Close(context.Background()))
+
+	assert.Contains(t, output.String(), "not forwarded")
+	assert.Contains(t, output.String(), "below threshold")
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if assert.Len(t, sink.alerts, 2) {
+		assert.Equal(t, "connection refused: db", sink.alerts[0].Title)
+		assert.Equal(t, SeverityError, sink.alerts[0].Severity)
+		assert.Equal(t, "auth-service", sink.alerts[0].Source)
+		assert.True(t, strings.HasPrefix(sink.alerts[0].Fields["caller"], "logger/alerting_logger_test.go:"))
+
+		assert.Equal(t, "invalid token", sink.alerts[1].Title)
+		assert.Equal(t, "42", sink.alerts[1].Fields["user"])
+	}
+}
+
+func TestAlertingLoggerThreshold(t *testing.T) {
+	sink := new(syncAlerter)
+	inner := NewSimpleLoggerWithConfig(Config{Level: LevelDebug, Output: new(strings.Builder)})
+
+	al := NewAlertingLogger(inner, AlertingConfig{
+		Sink:       sink,
+		Threshold:  LevelWarn,
+		RateLimit:  20 * time.Millisecond,
+		Dispatcher: NewAlertDispatcher(DispatcherConfig{GroupWindow: -1}),
+	})
+
+	al.Warn("disk at 90%")
+	time.Sleep(30 * time.Millisecond)
+	al.Named("disk").Warn("disk at 90%")
+	assert.NoError(t, al.Close(context.Background()))
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if assert.Len(t, sink.alerts, 2) {
+		assert.Equal(t, SeverityWarning, sink.alerts[0].Severity)
+		assert.Equal(t, "disk", sink.alerts[1].Fields["logger"])
+	}
+}
@@ -0,0 +1,29 @@
+// Package alertses sends the alerts by email through AWS SES. Importing it
+// registers the logger.SinkSES sink type, used in logger.AlertSinkConfig.
+package alertses
+
+import (
+	"github.com/tuckyapps/lit-go-tools/core/amazon"
+	"github.com/tuckyapps/lit-go-tools/logger"
+)
+
+func init() {
+	logger.RegisterSinkType(logger.SinkSES, func(config logger.AlertSinkConfig, settings logger.LogSettings) (logger.Alerter, error) {
+		return NewAlerter(config.From, config.To), nil
+	})
+}
+
+// NewAlerter creates an EmailAlerter that sends the emails through AWS SES;
+// amazon.Init must be called before sending alerts.
+func NewAlerter(from string, to []string) *logger.EmailAlerter {
+	var ea *logger.EmailAlerter
+	ea = logger.NewEmailAlerter(from, to, func(subject, text string) error {
+		return amazon.SES.SendEmail(&amazon.Email{
+			From:    ea.From,
+			To:      ea.To,
+			Subject: subject,
+			Text:    text,
+		})
+	})
+	return ea
+}
@@ -0,0 +1,27 @@
+package alertses
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/logger"
+)
+
+type testSettings struct{}
+
+func (testSettings) GetSlackEnabled() bool       { return false }
+func (testSettings) GetHTTPClient() *http.Client { return nil }
+func (testSettings) GetAppName() string          { return "test-app" }
+
+func TestSinkRegistered(t *testing.T) {
+	_, err := logger.NewAlertRouterFromConfig(logger.AlertRouterConfig{
+		Sinks:  []logger.AlertSinkConfig{{Name: "mail", Type: logger.SinkSES, From: "alerts@example.com", To: []string{"ops@example.com"}}},
+		Routes: []logger.AlertRoute{{Sinks: []string{"mail"}}},
+	}, testSettings{}, nil)
+	assert.NoError(t, err)
+
+	ea := NewAlerter("alerts@example.com", []string{"ops@example.com"})
+	assert.Equal(t, "alerts@example.com", ea.From)
+	assert.Equal(t, logger.SinkEmail+":alerts@example.com:ops@example.com", ea.ID())
+}
@@ -0,0 +1,364 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policies applied when the dispatcher queue is full
+const (
+	DropNewest = iota // the new alert is discarded
+	DropOldest        // the oldest queued alert is discarded to make room for the new one
+)
+
+// DispatcherConfig holds the options used to create an AlertDispatcher
+type DispatcherConfig struct {
+	// QueueSize is the max number of alerts waiting to be sent
+	QueueSize int
+
+	// DropPolicy decides which alert is discarded when the queue is full
+	DropPolicy int
+
+	// MaxRetries is the number of times a failed alert is retried. Negative
+	// disables retries.
+	MaxRetries int
+
+	// InitialBackoff is the wait before the first retry; it's doubled on each
+	// attempt, up to MaxBackoff. Retry-After from the sink takes precedence,
+	// capped at MaxBackoff too.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// SendTimeout limits the time of each attempt
+	SendTimeout time.Duration
+
+	// GroupWindow is the time during which identical alerts (same sink, title,
+	// text and severity) are sent once; the rest are counted and reported in a
+	// summary when the window ends. Negative disables grouping.
+	GroupWindow time.Duration
+
+	// OnError is called with the alerts that couldn't be sent
+	OnError func(alert Alert, err error)
+}
+
+// DefaultDispatcherConfig returns the configuration used when none is provided
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		QueueSize:      100,
+		DropPolicy:     DropNewest,
+		MaxRetries:     3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		SendTimeout:    10 * time.Second,
+		GroupWindow:    5 * time.Minute,
+	}
+}
+
+// AlertDispatcher sends the alerts in background, from a bounded queue. Failed
+// alerts are retried with backoff, and identical alerts are grouped.
+type AlertDispatcher struct {
+	config  DispatcherConfig
+	queue   chan dispatchJob
+	mutex   sync.Mutex
+	groups  map[string]*alertGroup
+	closed  bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stop    chan struct{}
+	done    chan struct{}
+	dropped uint64
+}
+
+// dispatchJob is an alert waiting to be sent
+type dispatchJob struct {
+	sink  Alerter
+	alert Alert
+}
+
+// alertGroup counts the identical alerts received in the current window
+type alertGroup struct {
+	job   dispatchJob
+	start time.Time
+	count int
+}
+
+// NewAlertDispatcher creates a dispatcher and starts its background tasks. Zero
+// values in config are replaced by the defaults.
+func NewAlertDispatcher(config DispatcherConfig) *AlertDispatcher {
+	defaults := DefaultDispatcherConfig()
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaults.QueueSize
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaults.MaxRetries
+	} else if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaults.InitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaults.MaxBackoff
+	}
+	if config.SendTimeout <= 0 {
+		config.SendTimeout = defaults.SendTimeout
+	}
+	if config.GroupWindow == 0 {
+		config.GroupWindow = defaults.GroupWindow
+	}
+
+	ad := &AlertDispatcher{
+		config: config,
+		queue:  make(chan dispatchJob, config.QueueSize),
+		groups: make(map[string]*alertGroup),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	ad.ctx, ad.cancel = context.WithCancel(context.Background())
+
+	go ad.sendLoop()
+	if config.GroupWindow > 0 {
+		go ad.groupLoop()
+	}
+
+	return ad
+}
+
+// Dispatch queues the alert to be sent through sink. It returns false if the
+// alert was discarded because the queue is full or the dispatcher is closed.
+// Alerts grouped with a previous one are not queued, but return true.
+func (ad *AlertDispatcher) Dispatch(sink Alerter, alert Alert) bool {
+	ad.mutex.Lock()
+	defer ad.mutex.Unlock()
+
+	if ad.closed {
+		return false
+	}
+
+	if alert.Time.IsZero() {
+		alert.Time = time.Now()
+	}
+	job := dispatchJob{sink: sink, alert: alert}
+
+	if ad.config.GroupWindow <= 0 {
+		return ad.enqueue(job)
+	}
+
+	key := groupKey(sink, alert)
+	if group, exists := ad.groups[key]; exists {
+		group.count++
+		return true
+	}
+
+	// the group starts with an alert that was actually queued, otherwise the
+	// following ones would only be reported in the summary
+	if !ad.enqueue(job) {
+		return false
+	}
+	ad.groups[key] = &alertGroup{job: job, start: time.Now()}
+	return true
+}
+
+// Dropped returns the number of alerts discarded because the queue was full
+func (ad *AlertDispatcher) Dropped() uint64 {
+	return atomic.LoadUint64(&ad.dropped)
+}
+
+// Close stops accepting alerts and waits until the queued ones are sent,
+// including the summaries of the grouped alerts. If ctx is done before that,
+// the pending alerts are discarded and ctx's error is returned.
+func (ad *AlertDispatcher) Close(ctx context.Context) error {
+	ad.mutex.Lock()
+	if !ad.closed {
+		ad.closed = true
+		close(ad.stop)
+
+		for key, group := range ad.groups {
+			if group.count > 0 {
+				ad.enqueue(summaryJob(group, time.Since(group.start)))
+			}
+			delete(ad.groups, key)
+		}
+		close(ad.queue)
+	}
+	ad.mutex.Unlock()
+
+	select {
+	case <-ad.done:
+		return nil
+	case <-ctx.Done():
+		ad.cancel()
+		<-ad.done
+		return ctx.Err()
+	}
+}
+
+// adds the job to the queue, applying the drop policy; mutex must be held
+func (ad *AlertDispatcher) enqueue(job dispatchJob) bool {
+	select {
+	case ad.queue <- job:
+		return true
+	default:
+	}
+
+	if ad.config.DropPolicy == DropOldest {
+		select {
+		case <-ad.queue:
+			atomic.AddUint64(&ad.dropped, 1)
+		default:
+		}
+
+		select {
+		case ad.queue <- job:
+			return true
+		default:
+		}
+	}
+
+	atomic.AddUint64(&ad.dropped, 1)
+	return false
+}
+
+func (ad *AlertDispatcher) sendLoop() {
+	defer close(ad.done)
+
+	for job := range ad.queue {
+		if ad.ctx.Err() != nil {
+			// closing without waiting, discard the rest of the queue
+			continue
+		}
+
+		if err := ad.send(job); err != nil && ad.config.OnError != nil {
+			ad.config.OnError(job.alert, err)
+		}
+	}
+}
+
+// sends the job, retrying temporary errors
+func (ad *AlertDispatcher) send(job dispatchJob) (err error) {
+	backoff := ad.config.InitialBackoff
+
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(ad.ctx, ad.config.SendTimeout)
+		err = job.sink.Send(ctx, job.alert)
+		cancel()
+
+		if err == nil || attempt >= ad.config.MaxRetries || !isTemporary(err) {
+			return
+		}
+
+		wait := backoff
+		var he *HTTPError
+		if errors.As(err, &he) && he.RetryAfter > 0 {
+			// capped, so a single response can't stall the queue
+			wait = he.RetryAfter
+			if wait > ad.config.MaxBackoff {
+				wait = ad.config.MaxBackoff
+			}
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ad.ctx.Done():
+			return
+		}
+
+		if backoff *= 2; backoff > ad.config.MaxBackoff {
+			backoff = ad.config.MaxBackoff
+		}
+	}
+}
+
+// checks periodically the groups whose window has ended
+func (ad *AlertDispatcher) groupLoop() {
+	interval := ad.config.GroupWindow / 10
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ad.flushGroups()
+		case <-ad.stop:
+			return
+		}
+	}
+}
+
+// sends the summaries of the groups whose window has ended
+func (ad *AlertDispatcher) flushGroups() {
+	ad.mutex.Lock()
+	defer ad.mutex.Unlock()
+
+	if ad.closed {
+		return
+	}
+
+	now := time.Now()
+	for key, group := range ad.groups {
+		if now.Sub(group.start) < ad.config.GroupWindow {
+			continue
+		}
+
+		if group.count > 0 {
+			ad.enqueue(summaryJob(group, ad.config.GroupWindow))
+
+			// keep grouping, since the alert is still being received
+			group.start = now
+			group.count = 0
+		} else {
+			delete(ad.groups, key)
+		}
+	}
+}
+
+// creates the alert reporting the grouped alerts
+func summaryJob(group *alertGroup, window time.Duration) dispatchJob {
+	job := group.job
+	job.alert.Title = fmt.Sprintf("%s (x%d in the last %s)", job.alert.Title, group.count, formatWindow(window))
+	job.alert.Time = time.Now()
+	return job
+}
+
+// returns the window as 5m, 1h, 30s...
+func formatWindow(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d >= time.Second:
+		return d.Round(time.Second).String()
+	default:
+		return d.Round(time.Millisecond).String()
+	}
+}
+
+// groupKey identifies the alert and its destination. Sinks that don't
+// implement IdentifiedAlerter are grouped by instance, so they should be reused.
+func groupKey(sink Alerter, alert Alert) string {
+	var sinkKey string
+	if ia, ok := sink.(IdentifiedAlerter); ok {
+		sinkKey = ia.ID()
+	} else {
+		sinkKey = fmt.Sprintf("%T:%p", sink, sink)
+	}
+	return fmt.Sprintf("%s\x00%d\x00%s\x00%s", sinkKey, alert.Severity, alert.Title, alert.Text)
+}
+
+// returns true if the error may not happen again if the alert is retried
+func isTemporary(err error) bool {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.Temporary()
+	}
+	return !errors.Is(err, ErrInvalidChannel) && !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,219 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syncAlerter records the alerts it receives; it's safe for concurrent use
+type syncAlerter struct {
+	mutex  sync.Mutex
+	alerts []Alert
+	block  chan struct{}
+}
+
+func (sa *syncAlerter) Send(ctx context.Context, alert Alert) error {
+	if sa.block != nil {
+		<-sa.block
+	}
+	sa.mutex.Lock()
+	defer sa.mutex.Unlock()
+	sa.alerts = append(sa.alerts, alert)
+	return nil
+}
+
+func (sa *syncAlerter) titles() []string {
+	sa.mutex.Lock()
+	defer sa.mutex.Unlock()
+	titles := make([]string, len(sa.alerts))
+	for i, a := range sa.alerts {
+		titles[i] = a.Title
+	}
+	return titles
+}
+
+func TestDispatcherGrouping(t *testing.T) {
+	sink := new(syncAlerter)
+	ad := NewAlertDispatcher(DispatcherConfig{GroupWindow: 50 * time.Millisecond})
+
+	for i := 0; i < 37; i++ {
+		ad.Dispatch(sink, Alert{Title: "db down", Severity: SeverityError})
+	}
+	ad.Dispatch(sink, Alert{Title: "other", Severity: SeverityError})
+
+	time.Sleep(150 * time.Millisecond)
+	assert.NoError(t, ad.Close(context.Background()))
+
+	titles := sink.titles()
+	assert.Equal(t, []string{"db down", "other", "db down (x36 in the last 50ms)"}, titles)
+}
+
+func TestDispatcherDropPolicy(t *testing.T) {
+	sink := &syncAlerter{block: make(chan struct{})}
+	ad := NewAlertDispatcher(DispatcherConfig{QueueSize: 2, DropPolicy: DropOldest, GroupWindow: -1})
+
+	// first alert is taken by the sender, which is blocked
+	ad.Dispatch(sink, Alert{Title: "0"})
+	time.Sleep(20 * time.Millisecond)
+
+	for _, title := range []string{"1", "2", "3", "4"} {
+		assert.True(t, ad.Dispatch(sink, Alert{Title: title}))
+	}
+	assert.Equal(t, uint64(2), ad.Dropped())
+
+	close(sink.block)
+	assert.NoError(t, ad.Close(context.Background()))
+	assert.Equal(t, []string{"0", "3", "4"}, sink.titles())
+
+	// closed dispatcher doesn't accept alerts
+	assert.False(t, ad.Dispatch(sink, Alert{Title: "5"}))
+}
+
+func TestDispatcherGroupingDropped(t *testing.T) {
+	sink := &syncAlerter{block: make(chan struct{})}
+	ad := NewAlertDispatcher(DispatcherConfig{QueueSize: 1, GroupWindow: time.Minute})
+
+	// first alert is taken by the sender, which is blocked, and the second one
+	// fills the queue
+	ad.Dispatch(sink, Alert{Title: "0"})
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, ad.Dispatch(sink, Alert{Title: "1"}))
+
+	// dropped alerts don't start a group
+	assert.False(t, ad.Dispatch(sink, Alert{Title: "db down"}))
+	assert.Equal(t, uint64(1), ad.Dropped())
+
+	close(sink.block)
+	for i := 0; i < 100 && len(sink.titles()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.True(t, ad.Dispatch(sink, Alert{Title: "db down"}))
+	assert.NoError(t, ad.Close(context.Background()))
+	assert.Equal(t, []string{"0", "1", "db down"}, sink.titles())
+}
+
+func TestDispatcherRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	var failed int32
+	ad := NewAlertDispatcher(DispatcherConfig{
+		InitialBackoff: time.Hour, // Retry-After must be used instead
+		OnError:        func(Alert, error) { atomic.AddInt32(&failed, 1) },
+	})
+
+	start := time.Now()
+	ad.Dispatch(NewSlackAlerter(server.URL, testSettings{enabled: true}), Alert{Title: "rate limited"})
+	assert.NoError(t, ad.Close(context.Background()))
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&failed))
+	assert.True(t, time.Since(start) >= time.Second)
+}
+
+func TestDispatcherRetryAfterCapped(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	ad := NewAlertDispatcher(DispatcherConfig{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+
+	start := time.Now()
+	ad.Dispatch(&WebhookAlerter{URL: server.URL}, Alert{Title: "rate limited"})
+	assert.NoError(t, ad.Close(context.Background()))
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestDispatcherGroupingBySinkID(t *testing.T) {
+	var mutex sync.Mutex
+	var titles []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		mutex.Lock()
+		defer mutex.Unlock()
+		titles = append(titles, payload.Title)
+	}))
+	defer server.Close()
+
+	ad := NewAlertDispatcher(DispatcherConfig{GroupWindow: 50 * time.Millisecond})
+
+	// a new sink on each call, as LogToSlack does
+	for i := 0; i < 3; i++ {
+		ad.Dispatch(&WebhookAlerter{URL: server.URL}, Alert{Title: "db down", Severity: SeverityError})
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	assert.NoError(t, ad.Close(context.Background()))
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []string{"db down", "db down (x2 in the last 50ms)"}, titles)
+}
+
+func TestDispatcherCloseTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ad := NewAlertDispatcher(DispatcherConfig{InitialBackoff: time.Hour})
+	ad.Dispatch(NewSlackAlerter(server.URL, testSettings{enabled: true}), Alert{Title: "unavailable"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, ad.Close(ctx))
+}
+
+func TestSimpleLoggerSlackQueue(t *testing.T) {
+	var mutex sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := new(strings.Builder)
+		b := make([]byte, 1024)
+		n, _ := r.Body.Read(b)
+		buf.Write(b[:n])
+		mutex.Lock()
+		bodies = append(bodies, buf.String())
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	sl := NewSimpleLoggerWithConfig(Config{Level: LevelInfo, Output: new(strings.Builder)})
+	settings := testSettings{enabled: true}
+
+	sl.LogToSlack(server.URL, "deploy", "version 1.2", settings)
+	sl.LogErrorToSlack(server.URL, "failure", "something broke", settings)
+	sl.LogToSlack(server.URL, "disabled", "not sent", testSettings{enabled: false})
+	assert.NoError(t, sl.Close(context.Background()))
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if assert.Len(t, bodies, 2) {
+		assert.Contains(t, bodies[0], `"title":"deploy"`)
+		assert.Contains(t, bodies[1], "```something broke```")
+	}
+}
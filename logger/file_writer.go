@@ -0,0 +1,383 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timestamp appended to the name of rotated files
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// extension added to compressed files
+const compressSuffix = ".gz"
+
+// FileWriterConfig holds the options used to create a FileWriter
+type FileWriterConfig struct {
+	// Filename is the file where the logs are written
+	Filename string
+
+	// MaxSize is the size in bytes that triggers a rotation (0 disables it)
+	MaxSize int64
+
+	// RotateEvery rotates the file periodically (0 disables it). Periods are
+	// aligned to UTC, so 24h rotates the file at midnight UTC.
+	RotateEvery time.Duration
+
+	// MaxBackups is the number of rotated files to keep (0 keeps all of them)
+	MaxBackups int
+
+	// MaxAge is the time rotated files are kept (0 keeps all of them)
+	MaxAge time.Duration
+
+	// Compress makes rotated files to be compressed with gzip
+	Compress bool
+
+	// ReopenOnSIGHUP makes the file to be reopened when the process receives
+	// SIGHUP, so it can be rotated by external tools like logrotate.
+	ReopenOnSIGHUP bool
+}
+
+// FileWriter is an io.Writer that writes to a file and rotates it by size and/or
+// time. Rotated files are renamed as name-<timestamp>.ext and, optionally,
+// compressed in background.
+//
+// It's safe for concurrent use, so it can be shared by all the loggers.
+type FileWriter struct {
+	config       FileWriterConfig
+	mutex        sync.Mutex
+	file         *os.File
+	size         int64
+	nextRotation time.Time
+	now          func() time.Time
+	cleanupCh    chan struct{}
+	closeCh      chan struct{}
+	closeOnce    sync.Once
+	closed       bool
+	wg           sync.WaitGroup
+}
+
+// NewFileWriter creates a FileWriter, opening (or creating) the configured file
+func NewFileWriter(config FileWriterConfig) (fw *FileWriter, err error) {
+	fw = &FileWriter{
+		config:    config,
+		now:       time.Now,
+		cleanupCh: make(chan struct{}, 1),
+		closeCh:   make(chan struct{}),
+	}
+
+	if err = fw.open(); err != nil {
+		return nil, err
+	}
+
+	fw.wg.Add(1)
+	go fw.cleanupLoop()
+
+	if config.ReopenOnSIGHUP {
+		fw.wg.Add(1)
+		go fw.reopenOnSignal()
+	}
+
+	// files left by a previous execution may have to be compressed or removed
+	fw.requestCleanup()
+	return
+}
+
+// Write writes p to the current file, rotating it first if needed. It returns
+// os.ErrClosed after Close.
+func (fw *FileWriter) Write(p []byte) (n int, err error) {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+
+	if fw.closed {
+		return 0, os.ErrClosed
+	}
+	if fw.file == nil {
+		if err = fw.open(); err != nil {
+			return
+		}
+	}
+
+	if fw.shouldRotate(int64(len(p))) {
+		if err = fw.rotate(); err != nil {
+			return
+		}
+	}
+
+	n, err = fw.file.Write(p)
+	fw.size += int64(n)
+	return
+}
+
+// Rotate closes the current file, renames it and starts a new one
+func (fw *FileWriter) Rotate() error {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+
+	if fw.closed {
+		return os.ErrClosed
+	}
+	return fw.rotate()
+}
+
+// Reopen closes and opens the file again, without renaming it. It's used when
+// the file was moved by an external tool.
+func (fw *FileWriter) Reopen() error {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+
+	if fw.closed {
+		return os.ErrClosed
+	}
+	if fw.file != nil {
+		fw.file.Close()
+		fw.file = nil
+	}
+	return fw.open()
+}
+
+// Close closes the current file and stops the background tasks
+func (fw *FileWriter) Close() (err error) {
+	fw.closeOnce.Do(func() {
+		close(fw.closeCh)
+	})
+	fw.wg.Wait()
+
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+
+	fw.closed = true
+	if fw.file != nil {
+		err = fw.file.Close()
+		fw.file = nil
+	}
+	return
+}
+
+// opens the configured file in append mode
+func (fw *FileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(fw.config.Filename), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(fw.config.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	fw.file = file
+	fw.size = info.Size()
+
+	if fw.config.RotateEvery > 0 {
+		fw.nextRotation = fw.now().Truncate(fw.config.RotateEvery).Add(fw.config.RotateEvery)
+	}
+	return nil
+}
+
+// returns true if writing n bytes requires a rotation
+func (fw *FileWriter) shouldRotate(n int64) bool {
+	if fw.config.MaxSize > 0 && fw.size > 0 && fw.size+n > fw.config.MaxSize {
+		return true
+	}
+	if fw.config.RotateEvery > 0 && !fw.now().Before(fw.nextRotation) {
+		return true
+	}
+	return false
+}
+
+func (fw *FileWriter) rotate() error {
+	if fw.file != nil {
+		if err := fw.file.Close(); err != nil {
+			return err
+		}
+		fw.file = nil
+	}
+
+	if err := os.Rename(fw.config.Filename, fw.backupName(fw.now())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := fw.open(); err != nil {
+		return err
+	}
+
+	fw.requestCleanup()
+	return nil
+}
+
+// returns the name used for a file rotated at t. If a file was already rotated
+// at the same time, a sequence number is added: name-<timestamp>-1.ext
+func (fw *FileWriter) backupName(t time.Time) string {
+	ext := filepath.Ext(fw.config.Filename)
+	prefix := strings.TrimSuffix(fw.config.Filename, ext) + "-" + t.Format(backupTimeFormat)
+
+	name := prefix + ext
+	for seq := 1; fileExists(name) || fileExists(name+compressSuffix); seq++ {
+		name = prefix + "-" + strconv.Itoa(seq) + ext
+	}
+	return name
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// backupFile represents a rotated file
+type backupFile struct {
+	path      string
+	rotatedAt time.Time
+	seq       int
+}
+
+// returns the rotated files, oldest first
+func (fw *FileWriter) backups() ([]backupFile, error) {
+	ext := filepath.Ext(fw.config.Filename)
+	prefix := strings.TrimSuffix(fw.config.Filename, ext)
+
+	matches, err := filepath.Glob(prefix + "-*")
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]backupFile, 0, len(matches))
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(m, prefix+"-"), compressSuffix)
+		stamp = strings.TrimSuffix(stamp, ext)
+		if b, ok := parseBackup(m, stamp); ok {
+			backups = append(backups, b)
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].rotatedAt.Equal(backups[j].rotatedAt) {
+			return backups[i].seq < backups[j].seq
+		}
+		return backups[i].rotatedAt.Before(backups[j].rotatedAt)
+	})
+	return backups, nil
+}
+
+// parses the timestamp of a backup, with its optional sequence number
+func parseBackup(path, stamp string) (b backupFile, ok bool) {
+	if len(stamp) < len(backupTimeFormat) {
+		return
+	}
+
+	rotatedAt, err := time.ParseInLocation(backupTimeFormat, stamp[:len(backupTimeFormat)], time.Local)
+	if err != nil {
+		return
+	}
+
+	seq := 0
+	if rest := stamp[len(backupTimeFormat):]; rest != "" {
+		if !strings.HasPrefix(rest, "-") {
+			return
+		}
+		if seq, err = strconv.Atoi(rest[1:]); err != nil || seq < 1 {
+			return
+		}
+	}
+
+	return backupFile{path: path, rotatedAt: rotatedAt, seq: seq}, true
+}
+
+// asks the background task to compress and remove the rotated files
+func (fw *FileWriter) requestCleanup() {
+	select {
+	case fw.cleanupCh <- struct{}{}:
+	default:
+		// a cleanup is already pending
+	}
+}
+
+func (fw *FileWriter) cleanupLoop() {
+	defer fw.wg.Done()
+
+	for {
+		select {
+		case <-fw.cleanupCh:
+			fw.cleanup()
+		case <-fw.closeCh:
+			// don't leave a pending cleanup behind
+			select {
+			case <-fw.cleanupCh:
+				fw.cleanup()
+			default:
+			}
+			return
+		}
+	}
+}
+
+// removes the backups exceeding MaxBackups or MaxAge, and compresses the rest
+func (fw *FileWriter) cleanup() {
+	backups, err := fw.backups()
+	if err != nil {
+		return
+	}
+
+	remove := 0
+	if fw.config.MaxBackups > 0 && len(backups) > fw.config.MaxBackups {
+		remove = len(backups) - fw.config.MaxBackups
+	}
+	if fw.config.MaxAge > 0 {
+		cutoff := fw.now().Add(-fw.config.MaxAge)
+		for remove < len(backups) && backups[remove].rotatedAt.Before(cutoff) {
+			remove++
+		}
+	}
+
+	for _, b := range backups[:remove] {
+		os.Remove(b.path)
+	}
+
+	if fw.config.Compress {
+		for _, b := range backups[remove:] {
+			if !strings.HasSuffix(b.path, compressSuffix) {
+				compressFile(b.path)
+			}
+		}
+	}
+}
+
+// compresses path into path.gz and removes the original file
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if errClose := dst.Close(); err == nil {
+		err = errClose
+	}
+
+	if err != nil {
+		os.Remove(path + compressSuffix)
+		return
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package logger
+
+// SIGHUP is not available on this platform, so the file is never reopened
+func (fw *FileWriter) reopenOnSignal() {
+	defer fw.wg.Done()
+	<-fw.closeCh
+}
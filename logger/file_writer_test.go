@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileWriterRotateBySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 4, 10, 12, 0, 0, 0, time.Local)
+	fw, err := NewFileWriter(FileWriterConfig{
+		Filename:   filepath.Join(dir, "app.log"),
+		MaxSize:    10,
+		MaxBackups: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for i := 0; i < 5; i++ {
+		fw.Write([]byte("123456789\n"))
+	}
+	fw.Close()
+
+	// current file + 2 backups (the oldest ones were removed)
+	files, _ := filepath.Glob(filepath.Join(dir, "app*.log"))
+	assert.Len(t, files, 3)
+}
+
+func TestFileWriterRotateByTimeAndCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var mutex sync.Mutex
+	now := time.Date(2020, 4, 10, 12, 0, 0, 0, time.Local)
+	clock := func() time.Time {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return now
+	}
+
+	fw, err := NewFileWriter(FileWriterConfig{
+		Filename:    filepath.Join(dir, "app.log"),
+		RotateEvery: time.Hour,
+		Compress:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.mutex.Lock()
+	fw.now = clock
+	fw.nextRotation = now.Truncate(time.Hour).Add(time.Hour)
+	fw.mutex.Unlock()
+
+	fw.Write([]byte("first hour\n"))
+
+	mutex.Lock()
+	now = now.Add(time.Hour)
+	mutex.Unlock()
+
+	fw.Write([]byte("second hour\n"))
+
+	// wait for the background compression
+	var compressed []string
+	for i := 0; i < 100 && len(compressed) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		compressed, _ = filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	}
+	fw.Close()
+
+	if assert.Len(t, compressed, 1) {
+		f, _ := os.Open(compressed[0])
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if assert.NoError(t, err) {
+			content, _ := ioutil.ReadAll(gz)
+			assert.Equal(t, "first hour\n", string(content))
+		}
+	}
+
+	current, _ := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.Equal(t, "second hour\n", string(current))
+}
+
+func TestFileWriterReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	fw, err := NewFileWriter(FileWriterConfig{Filename: filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+
+	fw.Write([]byte("before\n"))
+
+	// emulate logrotate
+	os.Rename(filename, filename+".1")
+	fw.Reopen()
+	fw.Write([]byte("after\n"))
+
+	content, _ := ioutil.ReadFile(filename)
+	assert.True(t, strings.HasPrefix(string(content), "after"))
+}
+
+func TestFileWriterWriteAfterClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	fw, err := NewFileWriter(FileWriterConfig{Filename: filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("before\n"))
+	fw.Close()
+
+	_, err = fw.Write([]byte("after\n"))
+	assert.Equal(t, os.ErrClosed, err)
+	assert.Equal(t, os.ErrClosed, fw.Rotate())
+	assert.Equal(t, os.ErrClosed, fw.Reopen())
+
+	content, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, "before\n", string(content))
+}
+
+func TestFileWriterUniqueBackupNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 4, 10, 12, 0, 0, 0, time.Local)
+	fw, err := NewFileWriter(FileWriterConfig{
+		Filename:   filepath.Join(dir, "app.log"),
+		MaxBackups: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.now = func() time.Time { return now }
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		fw.Write([]byte(line))
+		assert.NoError(t, fw.Rotate())
+	}
+	fw.Close()
+
+	// the oldest backup was removed, the others kept their content
+	files, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if assert.Len(t, files, 2) {
+		second, _ := ioutil.ReadFile(filepath.Join(dir, "app-2020-04-10T12-00-00.000-1.log"))
+		third, _ := ioutil.ReadFile(filepath.Join(dir, "app-2020-04-10T12-00-00.000-2.log"))
+		assert.Equal(t, "second\n", string(second))
+		assert.Equal(t, "third\n", string(third))
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// reopens the file every time the process receives SIGHUP
+func (fw *FileWriter) reopenOnSignal() {
+	defer fw.wg.Done()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+			fw.Reopen()
+		case <-fw.closeCh:
+			return
+		}
+	}
+}
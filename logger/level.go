@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// value stored in an AtomicLevel that follows its parent
+const inheritLevel = -1
+
+// ParseLevel converts a level name (error, warn, info, debug) or number to a log level
+func ParseLevel(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for level, name := range levelNames {
+		if name == s {
+			return level, nil
+		}
+	}
+
+	if level, err := strconv.Atoi(s); err == nil && level >= LevelError && level <= LevelWarn {
+		return level, nil
+	}
+
+	return 0, fmt.Errorf("invalid log level '%s'", s)
+}
+
+// Enabled returns true if the entries of level are logged when the current log
+// level is current, i.e. if level is as severe as current, or more
+func Enabled(current, level int) bool {
+	return severity(level) <= severity(current)
+}
+
+// returns the position of the level from the most severe to the least one
+func severity(level int) int {
+	switch level {
+	case LevelWarn:
+		return 1
+	case LevelInfo:
+		return 2
+	case LevelDebug:
+		return 3
+	default:
+		// LevelError, and levels out of range (more verbose than debug)
+		return level
+	}
+}
+
+// LevelName returns the name of the indicated level
+func LevelName(level int) string {
+	if name, exists := levelNames[level]; exists {
+		return name
+	}
+	return strconv.Itoa(level)
+}
+
+// AtomicLevel is a log level that can be changed while it's read by other
+// goroutines. A level created with a parent follows it until it's set.
+type AtomicLevel struct {
+	value  int32
+	parent *AtomicLevel
+}
+
+// NewAtomicLevel creates a new AtomicLevel with the indicated value
+func NewAtomicLevel(level int) *AtomicLevel {
+	return &AtomicLevel{value: int32(level)}
+}
+
+// Get returns the current level
+func (al *AtomicLevel) Get() int {
+	v := atomic.LoadInt32(&al.value)
+	if v == inheritLevel && al.parent != nil {
+		return al.parent.Get()
+	}
+	return int(v)
+}
+
+// Set changes the current level
+func (al *AtomicLevel) Set(level int) {
+	atomic.StoreInt32(&al.value, int32(level))
+}
+
+// Reset makes the level to follow its parent again
+func (al *AtomicLevel) Reset() {
+	if al.parent != nil {
+		atomic.StoreInt32(&al.value, inheritLevel)
+	}
+}
+
+// IsInherited returns true if the level is taken from the parent
+func (al *AtomicLevel) IsInherited() bool {
+	return al.parent != nil && atomic.LoadInt32(&al.value) == inheritLevel
+}
+
+// LevelRegistry holds the root level of a logger and the levels of its named
+// children. Named levels follow the root one until they're explicitly set.
+type LevelRegistry struct {
+	root    *AtomicLevel
+	mutex   sync.Mutex
+	named   map[string]*AtomicLevel
+	reverts map[string]*levelRevert
+}
+
+// levelRevert is a scheduled restore of a previous level
+type levelRevert struct {
+	timer    *time.Timer
+	previous int32
+	at       time.Time
+}
+
+// NewLevelRegistry creates a registry with the indicated root level
+func NewLevelRegistry(rootLevel int) *LevelRegistry {
+	return &LevelRegistry{
+		root:    NewAtomicLevel(rootLevel),
+		named:   make(map[string]*AtomicLevel),
+		reverts: make(map[string]*levelRevert),
+	}
+}
+
+// Root returns the root level
+func (lr *LevelRegistry) Root() *AtomicLevel {
+	return lr.root
+}
+
+// Level returns the level for the named logger, creating it if needed. The
+// empty name refers to the root level.
+func (lr *LevelRegistry) Level(name string) *AtomicLevel {
+	if name == "" {
+		return lr.root
+	}
+
+	lr.mutex.Lock()
+	defer lr.mutex.Unlock()
+
+	al, exists := lr.named[name]
+	if !exists {
+		al = &AtomicLevel{value: inheritLevel, parent: lr.root}
+		lr.named[name] = al
+	}
+	return al
+}
+
+// Set changes the level of the named logger (or root, if name is empty). If
+// revertAfter is greater than zero, the previous level is restored once that
+// time has passed.
+func (lr *LevelRegistry) Set(name string, level int, revertAfter time.Duration) {
+	al := lr.Level(name)
+
+	lr.mutex.Lock()
+	defer lr.mutex.Unlock()
+
+	// a pending revert is cancelled, but its original level is kept
+	previous := atomic.LoadInt32(&al.value)
+	if revert, exists := lr.reverts[name]; exists {
+		revert.timer.Stop()
+		previous = revert.previous
+		delete(lr.reverts, name)
+	}
+
+	al.Set(level)
+
+	if revertAfter > 0 {
+		revert := &levelRevert{previous: previous, at: time.Now().Add(revertAfter)}
+		revert.timer = time.AfterFunc(revertAfter, func() {
+			lr.mutex.Lock()
+			defer lr.mutex.Unlock()
+
+			// ignore the revert if it was replaced or cancelled in the meantime
+			if lr.reverts[name] == revert {
+				atomic.StoreInt32(&al.value, revert.previous)
+				delete(lr.reverts, name)
+			}
+		})
+		lr.reverts[name] = revert
+	}
+}
+
+// Reset makes the named logger to follow the root level again
+func (lr *LevelRegistry) Reset(name string) {
+	al := lr.Level(name)
+
+	lr.mutex.Lock()
+	defer lr.mutex.Unlock()
+
+	if revert, exists := lr.reverts[name]; exists {
+		revert.timer.Stop()
+		delete(lr.reverts, name)
+	}
+	al.Reset()
+}
+
+// LevelStatus describes the level of a logger
+type LevelStatus struct {
+	Level     string     `json:"level"`
+	Inherited bool       `json:"inherited,omitempty"`
+	RevertsAt *time.Time `json:"reverts_at,omitempty"`
+}
+
+// Status returns the current level of the root ("") and named loggers
+func (lr *LevelRegistry) Status() map[string]LevelStatus {
+	lr.mutex.Lock()
+	defer lr.mutex.Unlock()
+
+	status := make(map[string]LevelStatus, len(lr.named)+1)
+	status[""] = LevelStatus{Level: LevelName(lr.root.Get())}
+
+	for name, al := range lr.named {
+		status[name] = LevelStatus{Level: LevelName(al.Get()), Inherited: al.IsInherited()}
+	}
+
+	for name, revert := range lr.reverts {
+		st := status[name]
+		at := revert.at
+		st.RevertsAt = &at
+		status[name] = st
+	}
+
+	return status
+}
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedLoggerLevels(t *testing.T) {
+	buf := new(bytes.Buffer)
+	sl := NewSimpleLoggerWithConfig(Config{Level: LevelInfo, Output: buf})
+	ds := sl.Named("datasource")
+
+	// named loggers follow the root level until they're changed
+	assert.Equal(t, LevelInfo, ds.GetLevel())
+	sl.SetLevel(LevelWarn)
+	assert.Equal(t, LevelWarn, ds.GetLevel())
+
+	ds.SetLevel(LevelDebug)
+	assert.Equal(t, LevelDebug, ds.GetLevel())
+	assert.Equal(t, LevelWarn, sl.GetLevel())
+
+	ds.Debug("query executed")
+	sl.Info("not logged")
+	assert.Contains(t, buf.String(), "query executed logger=datasource")
+	assert.NotContains(t, buf.String(), "not logged")
+
+	// same name, same level
+	assert.Equal(t, LevelDebug, sl.Named("datasource").GetLevel())
+
+	sl.Levels().Reset("datasource")
+	assert.Equal(t, LevelWarn, ds.GetLevel())
+}
+
+func TestLevelRegistryRevert(t *testing.T) {
+	lr := NewLevelRegistry(LevelInfo)
+
+	lr.Set("", LevelDebug, 20*time.Millisecond)
+	lr.Set("", LevelError, 20*time.Millisecond)
+	assert.Equal(t, LevelError, lr.Root().Get())
+	assert.NotNil(t, lr.Status()[""].RevertsAt)
+
+	// the original level is restored, not the intermediate one
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, LevelInfo, lr.Root().Get())
+	assert.Nil(t, lr.Status()[""].RevertsAt)
+}
+
+func TestLevelConcurrentAccess(t *testing.T) {
+	sl := NewSimpleLoggerWithConfig(Config{Level: LevelInfo, Output: new(bytes.Buffer)})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			sl.SetLevel(i % (LevelWarn + 1))
+		}(i)
+		go func() {
+			defer wg.Done()
+			sl.GetLevel()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLevelHandler(t *testing.T) {
+	lr := NewLevelRegistry(LevelInfo)
+	handler := LevelHandler(lr)
+
+	body := strings.NewReader(`{"logger":"datasource","level":"debug","revert_after":"1h"}`)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log/level", body))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp levelsResponse
+	if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp)) {
+		assert.Equal(t, "info", resp.Root.Level)
+		assert.Equal(t, "debug", resp.Loggers["datasource"].Level)
+		assert.NotNil(t, resp.Loggers["datasource"].RevertsAt)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log/level?level=verbose", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/log/level?logger=datasource", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, lr.Level("datasource").IsInherited())
+}
+
+func TestLevelValues(t *testing.T) {
+	// numbers used before LevelWarn was added keep their meaning
+	for s, level := range map[string]int{"0": LevelError, "1": LevelInfo, "2": LevelDebug, "3": LevelWarn, "warn": LevelWarn} {
+		parsed, err := ParseLevel(s)
+		assert.NoError(t, err)
+		assert.Equal(t, level, parsed, s)
+	}
+	_, err := ParseLevel("4")
+	assert.Error(t, err)
+
+	assert.True(t, Enabled(LevelWarn, LevelError))
+	assert.True(t, Enabled(LevelWarn, LevelWarn))
+	assert.False(t, Enabled(LevelWarn, LevelInfo))
+	assert.True(t, Enabled(LevelInfo, LevelWarn))
+	assert.False(t, Enabled(LevelError, LevelWarn))
+	assert.True(t, Enabled(LevelDebug, LevelInfo))
+
+	buf := new(bytes.Buffer)
+	sl := NewSimpleLoggerWithConfig(Config{Level: LevelWarn, Output: buf})
+	sl.Info("hidden")
+	sl.Warn("shown")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "shown")
+}
@@ -1,12 +1,19 @@
 package logger
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+	"sync"
+)
 
-// Default log levels
+// Default log levels. LevelWarn was added after the others, so it keeps their
+// numbers (e.g. in config files): it's between LevelError and LevelInfo in
+// severity, but not in value. Use Enabled to compare levels.
 const (
-	LevelError = iota
-	LevelInfo
-	LevelDebug
+	LevelError = 0
+	LevelInfo  = 1
+	LevelDebug = 2
+	LevelWarn  = 3
 )
 
 // Logger presents a common interface for logger
@@ -15,6 +22,8 @@ type Logger interface {
 	Debugf(format string, v ...interface{})
 	Info(v ...interface{})
 	Infof(format string, v ...interface{})
+	Warn(v ...interface{})
+	Warnf(format string, v ...interface{})
 	Error(v ...interface{})
 	Errorf(format string, v ...interface{})
 	Fatal(v ...interface{})
@@ -22,11 +31,21 @@ type Logger interface {
 	Print(v ...interface{})
 	SetLevel(level int)
 	GetLevel() int
+	WithFields(fields map[string]interface{}) Logger
+	With(key string, value interface{}) Logger
 	LogToSlack(webHook, title, text string, logSettings LogSettings)
 	LogErrorToSlack(webHook, title, text string, logSettings LogSettings)
 }
 
-//LogSettings interface to be implemented by other project settings.
+// NamedLogger is implemented by loggers that support named children with their
+// own log level
+type NamedLogger interface {
+	Logger
+	Named(name string) Logger
+	Levels() *LevelRegistry
+}
+
+// LogSettings interface to be implemented by other project settings.
 type LogSettings interface {
 	GetSlackEnabled() bool
 	GetHTTPClient() (client *http.Client)
@@ -34,25 +53,68 @@ type LogSettings interface {
 }
 
 var (
-	logImpl Logger
+	logImpl  Logger
+	logMutex sync.RWMutex
 )
 
 // SetLogger sets the current logger
 func SetLogger(l Logger) {
+	logMutex.Lock()
+	defer logMutex.Unlock()
 	logImpl = l
 }
 
 // GetLogger returns the current logger defined for the service
 func GetLogger() Logger {
-	if logImpl == nil {
-		logImpl = NewSimpleLogger()
+	logMutex.RLock()
+	l := logImpl
+	logMutex.RUnlock()
+
+	if l == nil {
+		logMutex.Lock()
+		defer logMutex.Unlock()
+
+		if logImpl == nil {
+			logImpl = NewSimpleLogger()
+		}
+		l = logImpl
 	}
-	return logImpl
+	return l
 }
 
 // SetLogLevel configures the application's log level
 func SetLogLevel(level int) {
+	logMutex.RLock()
+	defer logMutex.RUnlock()
+
 	if logImpl != nil {
 		logImpl.SetLevel(level)
 	}
 }
+
+// Named returns a child of the current logger with its own log level. If the
+// current logger doesn't support named loggers, it's returned as is.
+func Named(name string) Logger {
+	if nl, ok := GetLogger().(NamedLogger); ok {
+		return nl.Named(name)
+	}
+	return GetLogger()
+}
+
+// Levels returns the level registry of the current logger, or nil if it doesn't
+// support named loggers
+func Levels() *LevelRegistry {
+	if nl, ok := GetLogger().(NamedLogger); ok {
+		return nl.Levels()
+	}
+	return nil
+}
+
+// Close releases the resources of the current logger, like the queued alerts,
+// if it supports it. It should be called when the service ends.
+func Close(ctx context.Context) error {
+	if c, ok := GetLogger().(interface{ Close(context.Context) error }); ok {
+		return c.Close(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package loggertest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tuckyapps/lit-go-tools/logger"
+)
+
+// AlertRecorder is a logger.Alerter that keeps the alerts in memory, instead of
+// sending them. Err, if set, is returned by Send.
+type AlertRecorder struct {
+	Err error
+
+	mutex  sync.Mutex
+	alerts []logger.Alert
+}
+
+// Send records the alert
+func (ar *AlertRecorder) Send(ctx context.Context, alert logger.Alert) error {
+	ar.mutex.Lock()
+	defer ar.mutex.Unlock()
+
+	ar.alerts = append(ar.alerts, alert)
+	return ar.Err
+}
+
+// Alerts returns the recorded alerts, in order
+func (ar *AlertRecorder) Alerts() []logger.Alert {
+	ar.mutex.Lock()
+	defer ar.mutex.Unlock()
+	return append([]logger.Alert(nil), ar.alerts...)
+}
+
+// Reset discards the recorded alerts
+func (ar *AlertRecorder) Reset() {
+	ar.mutex.Lock()
+	defer ar.mutex.Unlock()
+	ar.alerts = nil
+}
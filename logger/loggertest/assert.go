@@ -0,0 +1,94 @@
+package loggertest
+
+import (
+	"strings"
+
+	"github.com/tuckyapps/lit-go-tools/logger"
+)
+
+// TestingT is the subset of testing.T used by the assertions
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+	Helper()
+}
+
+// AssertLogged checks that the current logger, set with Install, recorded an
+// entry of the level that has text in its message or in any field value.
+func AssertLogged(t TestingT, level int, text string) bool {
+	t.Helper()
+
+	r, ok := current(t)
+	return ok && r.AssertLogged(t, level, text)
+}
+
+// AssertNotLogged checks that the current logger, set with Install, didn't
+// record any entry of the level that has text in its message or field values.
+func AssertNotLogged(t TestingT, level int, text string) bool {
+	t.Helper()
+
+	r, ok := current(t)
+	return ok && r.AssertNotLogged(t, level, text)
+}
+
+// AssertLogged checks that r recorded an entry of the level that has text in
+// its message or in any field value.
+func (r *Recorder) AssertLogged(t TestingT, level int, text string) bool {
+	t.Helper()
+
+	if !r.Logged(level, text) {
+		t.Errorf("no %s entry contains %q; recorded entries:\n%s", logger.LevelName(level), text, r.dump())
+		return false
+	}
+	return true
+}
+
+// AssertNotLogged checks that r didn't record any entry of the level that has
+// text in its message or field values.
+func (r *Recorder) AssertNotLogged(t TestingT, level int, text string) bool {
+	t.Helper()
+
+	if r.Logged(level, text) {
+		t.Errorf("unexpected %s entry containing %q; recorded entries:\n%s", logger.LevelName(level), text, r.dump())
+		return false
+	}
+	return true
+}
+
+// AssertSlackSent checks that r recorded a Slack message whose title or text
+// contains text.
+func (r *Recorder) AssertSlackSent(t TestingT, text string) bool {
+	t.Helper()
+
+	for _, m := range r.SlackMessages() {
+		if strings.Contains(m.Title, text) || strings.Contains(m.Text, text) {
+			return true
+		}
+	}
+	t.Errorf("no Slack message contains %q", text)
+	return false
+}
+
+// returns the current logger as a Recorder
+func current(t TestingT) (*Recorder, bool) {
+	t.Helper()
+
+	r, ok := logger.GetLogger().(*Recorder)
+	if !ok {
+		t.Errorf("the current logger is not a loggertest.Recorder; call loggertest.Install first")
+	}
+	return r, ok
+}
+
+// returns the recorded entries, one per line
+func (r *Recorder) dump() string {
+	entries := r.Entries()
+	if len(entries) == 0 {
+		return "\t(none)"
+	}
+
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = "\t" + e.String()
+	}
+	return strings.Join(lines, "\n")
+}
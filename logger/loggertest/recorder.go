@@ -0,0 +1,293 @@
+// Package loggertest provides test doubles for the logger package: a Recorder
+// that keeps the entries in memory, assertions over them, and fake alert sinks.
+//
+// Typical usage:
+//
+//	rec, restore := loggertest.Install()
+//	defer restore()
+//
+//	service.Login("invalid")
+//	loggertest.AssertLogged(t, logger.LevelError, "auth-service")
+package loggertest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tuckyapps/lit-go-tools/logger"
+)
+
+// Entry is a recorded log entry
+type Entry struct {
+	Level   int
+	Message string
+	Fields  map[string]interface{}
+	Fatal   bool // logged with Fatal or Fatalf
+}
+
+// String returns the entry as: [LEVEL] message key1=value1 key2=value2
+func (e Entry) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("[%s] %s", strings.ToUpper(logger.LevelName(e.Level)), e.Message))
+	for _, k := range sortedNames(e.Fields) {
+		sb.WriteString(fmt.Sprintf(" %s=%v", k, e.Fields[k]))
+	}
+	return sb.String()
+}
+
+// matches returns true if the message or any field value contains text
+func (e Entry) matches(text string) bool {
+	if strings.Contains(e.Message, text) {
+		return true
+	}
+	for _, v := range e.Fields {
+		if strings.Contains(fmt.Sprint(v), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// SlackMessage is a message that LogToSlack or LogErrorToSlack would have sent
+type SlackMessage struct {
+	WebHook string
+	Title   string
+	Text    string
+	Error   bool // sent with LogErrorToSlack
+}
+
+// FatalPanic is the value of the panic raised by Recorder's Fatal and Fatalf,
+// instead of exiting the process
+type FatalPanic struct {
+	Message string
+}
+
+// recorderStore holds the entries shared by a recorder and its children
+type recorderStore struct {
+	mutex   sync.Mutex
+	entries []Entry
+	slack   []SlackMessage
+	levels  *logger.LevelRegistry
+}
+
+// Recorder is a logger.Logger that keeps the entries in memory, so tests can
+// check what was logged. Children created with With, WithFields and Named
+// record into the same Recorder. It's safe for concurrent use.
+type Recorder struct {
+	store  *recorderStore
+	name   string
+	level  *logger.AtomicLevel
+	fields map[string]interface{}
+}
+
+// New creates a Recorder that records all the levels
+func New() *Recorder {
+	levels := logger.NewLevelRegistry(logger.LevelDebug)
+	return &Recorder{
+		store: &recorderStore{levels: levels},
+		level: levels.Root(),
+	}
+}
+
+// Install creates a Recorder and sets it as the current logger. The returned
+// function restores the previous logger.
+func Install() (*Recorder, func()) {
+	previous := logger.GetLogger()
+	r := New()
+	logger.SetLogger(r)
+
+	return r, func() {
+		logger.SetLogger(previous)
+	}
+}
+
+func (r *Recorder) record(level int, msg string, fatal bool) {
+	if !fatal && !logger.Enabled(r.level.Get(), level) {
+		return
+	}
+
+	entry := Entry{Level: level, Message: msg, Fatal: fatal, Fields: make(map[string]interface{}, len(r.fields))}
+	for k, v := range r.fields {
+		entry.Fields[k] = v
+	}
+
+	r.store.mutex.Lock()
+	r.store.entries = append(r.store.entries, entry)
+	r.store.mutex.Unlock()
+}
+
+// Debug records the arguments at debug level
+func (r *Recorder) Debug(v ...interface{}) {
+	r.record(logger.LevelDebug, fmt.Sprint(v...), false)
+}
+
+// Debugf records the arguments at debug level, handled like in fmt.Printf
+func (r *Recorder) Debugf(format string, v ...interface{}) {
+	r.record(logger.LevelDebug, fmt.Sprintf(format, v...), false)
+}
+
+// Info records the arguments at info level
+func (r *Recorder) Info(v ...interface{}) {
+	r.record(logger.LevelInfo, fmt.Sprint(v...), false)
+}
+
+// Infof records the arguments at info level, handled like in fmt.Printf
+func (r *Recorder) Infof(format string, v ...interface{}) {
+	r.record(logger.LevelInfo, fmt.Sprintf(format, v...), false)
+}
+
+// Warn records the arguments at warning level
+func (r *Recorder) Warn(v ...interface{}) {
+	r.record(logger.LevelWarn, fmt.Sprint(v...), false)
+}
+
+// Warnf records the arguments at warning level, handled like in fmt.Printf
+func (r *Recorder) Warnf(format string, v ...interface{}) {
+	r.record(logger.LevelWarn, fmt.Sprintf(format, v...), false)
+}
+
+// Error records the arguments at error level
+func (r *Recorder) Error(v ...interface{}) {
+	r.record(logger.LevelError, fmt.Sprint(v...), false)
+}
+
+// Errorf records the arguments at error level, handled like in fmt.Printf
+func (r *Recorder) Errorf(format string, v ...interface{}) {
+	r.record(logger.LevelError, fmt.Sprintf(format, v...), false)
+}
+
+// Fatal records the arguments at error level and panics with a FatalPanic,
+// instead of exiting the process
+func (r *Recorder) Fatal(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	r.record(logger.LevelError, msg, true)
+	panic(FatalPanic{Message: msg})
+}
+
+// Fatalf is like Fatal, but arguments are handled like in fmt.Printf
+func (r *Recorder) Fatalf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	r.record(logger.LevelError, msg, true)
+	panic(FatalPanic{Message: msg})
+}
+
+// Print records the arguments at info level
+func (r *Recorder) Print(v ...interface{}) {
+	r.record(logger.LevelInfo, fmt.Sprint(v...), false)
+}
+
+// SetLevel sets the level of the entries recorded; less severe ones are ignored
+func (r *Recorder) SetLevel(level int) {
+	r.store.levels.Set(r.name, level, 0)
+}
+
+// GetLevel returns the current level
+func (r *Recorder) GetLevel() int {
+	return r.level.Get()
+}
+
+// WithFields returns a child that includes the fields in its entries
+func (r *Recorder) WithFields(fields map[string]interface{}) logger.Logger {
+	child := *r
+	child.fields = make(map[string]interface{}, len(r.fields)+len(fields))
+	for k, v := range r.fields {
+		child.fields[k] = v
+	}
+	for k, v := range fields {
+		child.fields[k] = v
+	}
+	return &child
+}
+
+// With returns a child that includes the field key=value in its entries
+func (r *Recorder) With(key string, value interface{}) logger.Logger {
+	return r.WithFields(map[string]interface{}{key: value})
+}
+
+// Named returns a child with its own level, including the name in the 'logger' field
+func (r *Recorder) Named(name string) logger.Logger {
+	child := r.With("logger", name).(*Recorder)
+	child.name = name
+	child.level = r.store.levels.Level(name)
+	return child
+}
+
+// Levels returns the registry with the levels of the recorder and its named children
+func (r *Recorder) Levels() *logger.LevelRegistry {
+	return r.store.levels
+}
+
+// LogToSlack records the message, if Slack is enabled in logSettings
+func (r *Recorder) LogToSlack(webHook, title, text string, logSettings logger.LogSettings) {
+	r.recordSlack(webHook, title, text, false, logSettings)
+}
+
+// LogErrorToSlack records the error message, if Slack is enabled in logSettings
+func (r *Recorder) LogErrorToSlack(webHook, title, text string, logSettings logger.LogSettings) {
+	r.recordSlack(webHook, title, text, true, logSettings)
+}
+
+func (r *Recorder) recordSlack(webHook, title, text string, isError bool, logSettings logger.LogSettings) {
+	if logSettings != nil && !logSettings.GetSlackEnabled() {
+		return
+	}
+
+	r.store.mutex.Lock()
+	r.store.slack = append(r.store.slack, SlackMessage{WebHook: webHook, Title: title, Text: text, Error: isError})
+	r.store.mutex.Unlock()
+}
+
+// Entries returns the recorded entries, in order
+func (r *Recorder) Entries() []Entry {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+	return append([]Entry(nil), r.store.entries...)
+}
+
+// EntriesAt returns the recorded entries of the level
+func (r *Recorder) EntriesAt(level int) []Entry {
+	var entries []Entry
+	for _, e := range r.Entries() {
+		if e.Level == level {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// SlackMessages returns the messages sent with LogToSlack and LogErrorToSlack
+func (r *Recorder) SlackMessages() []SlackMessage {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+	return append([]SlackMessage(nil), r.store.slack...)
+}
+
+// Reset discards the recorded entries and Slack messages
+func (r *Recorder) Reset() {
+	r.store.mutex.Lock()
+	defer r.store.mutex.Unlock()
+	r.store.entries = nil
+	r.store.slack = nil
+}
+
+// Logged returns true if an entry of the level has text in its message or in
+// any field value
+func (r *Recorder) Logged(level int, text string) bool {
+	for _, e := range r.Entries() {
+		if e.Level == level && e.matches(text) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedNames(fields map[string]interface{}) []string {
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
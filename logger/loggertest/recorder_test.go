@@ -0,0 +1,73 @@
+package loggertest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/logger"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (ft *fakeT) Errorf(format string, args ...interface{}) {
+	ft.errors = append(ft.errors, fmt.Sprintf(format, args...))
+}
+
+func (ft *fakeT) Helper() {}
+
+type slackSettings bool
+
+func (ss slackSettings) GetSlackEnabled() bool { return bool(ss) }
+func (ss slackSettings) GetHTTPClient() *http.Client {
+	return nil
+}
+func (ss slackSettings) GetAppName() string { return "test" }
+
+func TestRecorder(t *testing.T) {
+	rec, restore := Install()
+	defer restore()
+
+	logger.GetLogger().With("service", "auth-service").Errorf("login failed: %s", "bad password")
+	logger.Named("db").Debug("query")
+	rec.Named("db").SetLevel(logger.LevelInfo)
+	logger.Named("db").Debug("ignored")
+
+	AssertLogged(t, logger.LevelError, "auth-service")
+	AssertLogged(t, logger.LevelError, "bad password")
+	AssertNotLogged(t, logger.LevelDebug, "ignored")
+	assert.Len(t, rec.EntriesAt(logger.LevelDebug), 1)
+
+	ft := new(fakeT)
+	assert.False(t, rec.AssertLogged(ft, logger.LevelWarn, "auth-service"))
+	if assert.Len(t, ft.errors, 1) {
+		assert.Contains(t, ft.errors[0], "[ERROR] login failed: bad password service=auth-service")
+	}
+
+	assert.Panics(t, func() { rec.Fatal("boom") })
+	assert.True(t, rec.Entries()[2].Fatal)
+
+	rec.LogErrorToSlack("hook", "failure", "details", slackSettings(true))
+	rec.LogToSlack("hook", "disabled", "", slackSettings(false))
+	assert.Equal(t, []SlackMessage{{WebHook: "hook", Title: "failure", Text: "details", Error: true}}, rec.SlackMessages())
+	rec.AssertSlackSent(t, "details")
+
+	rec.Reset()
+	assert.Empty(t, rec.Entries())
+}
+
+func TestAlertRecorder(t *testing.T) {
+	sink := new(AlertRecorder)
+	al := logger.NewAlertingLogger(New(), logger.AlertingConfig{Sink: sink})
+
+	al.Error("disk full")
+	assert.NoError(t, al.Close(context.Background()))
+
+	if alerts := sink.Alerts(); assert.Len(t, alerts, 1) {
+		assert.Equal(t, "disk full", alerts[0].Title)
+	}
+}
@@ -1,113 +1,362 @@
 package logger
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
+// Output formats supported by SimpleLogger
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// names used for the log levels in JSON output
+var levelNames = map[int]string{
+	LevelError: "error",
+	LevelWarn:  "warn",
+	LevelInfo:  "info",
+	LevelDebug: "debug",
+}
+
+// Config holds the options used to create a SimpleLogger
+type Config struct {
+	// Level is the initial log level
+	Level int
+
+	// Format is the output format, FormatText or FormatJSON (default is FormatText)
+	Format string
+
+	// Output is the writer where the logs are sent (default is os.Stdout). If it
+	// implements LevelWriter, the level of each entry is passed along.
+	Output io.Writer
+
+	// Alerts configures the dispatcher used by LogToSlack and LogErrorToSlack
+	// (DefaultDispatcherConfig if not set)
+	Alerts *DispatcherConfig
+}
+
+// DefaultConfig returns the configuration used by NewSimpleLogger
+func DefaultConfig() Config {
+	return Config{
+		Level:  LevelInfo,
+		Format: FormatText,
+		Output: os.Stdout,
+	}
+}
+
+// LevelWriter is implemented by writers that handle the entries differently
+// depending on their log level (e.g. syslog)
+type LevelWriter interface {
+	WriteLevel(level int, p []byte) (n int, err error)
+}
+
+// levelWriter adapts a LevelWriter to an io.Writer for a fixed level
+type levelWriter struct {
+	w     LevelWriter
+	level int
+}
+
+func (lw levelWriter) Write(p []byte) (n int, err error) {
+	return lw.w.WriteLevel(lw.level, p)
+}
+
 // SimpleLogger is the standar logger implementation
 type SimpleLogger struct {
 	debugLogger *log.Logger
 	infoLogger  *log.Logger
+	warnLogger  *log.Logger
 	errorLogger *log.Logger
-	level       int
+	levels      *LevelRegistry
+	level       *AtomicLevel
+	name        string
+	format      string
+	fields      map[string]interface{}
+	alerts      *alertState
+}
+
+// alertState holds the dispatcher shared by a logger and its children; it's
+// created when the first alert is sent
+type alertState struct {
+	once       sync.Once
+	config     DispatcherConfig
+	dispatcher *AlertDispatcher
 }
 
 // NewSimpleLogger creates a new instance of SimpleLogger.
 func NewSimpleLogger() (sl *SimpleLogger) {
+	return NewSimpleLoggerWithConfig(DefaultConfig())
+}
+
+// NewSimpleLoggerWithConfig creates a new instance of SimpleLogger using the
+// indicated configuration.
+func NewSimpleLoggerWithConfig(config Config) (sl *SimpleLogger) {
 	sl = new(SimpleLogger)
-	sl.level = LevelInfo
+	sl.levels = NewLevelRegistry(config.Level)
+	sl.level = sl.levels.Root()
 
-	sl.debugLogger = log.New(os.Stdout, "[DBG] ", log.LstdFlags)
-	sl.infoLogger = log.New(os.Stdout, "[INF] ", log.LstdFlags)
-	sl.errorLogger = log.New(os.Stdout, "[ERR] ", log.LstdFlags)
+	sl.alerts = &alertState{config: DefaultDispatcherConfig()}
+	if config.Alerts != nil {
+		sl.alerts.config = *config.Alerts
+	}
+
+	sl.format = config.Format
+	if sl.format == "" {
+		sl.format = FormatText
+	}
+
+	out := config.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	sl.debugLogger = sl.newLogger(out, LevelDebug, "[DBG] ")
+	sl.infoLogger = sl.newLogger(out, LevelInfo, "[INF] ")
+	sl.warnLogger = sl.newLogger(out, LevelWarn, "[WRN] ")
+	sl.errorLogger = sl.newLogger(out, LevelError, "[ERR] ")
 	return
 }
 
+// creates the logger for the specified level; JSON entries include their
+// own timestamp and level, so no prefix or flags are used
+func (sl *SimpleLogger) newLogger(out io.Writer, level int, prefix string) *log.Logger {
+	if lw, ok := out.(LevelWriter); ok {
+		out = levelWriter{w: lw, level: level}
+	}
+
+	if sl.format == FormatJSON {
+		return log.New(out, "", 0)
+	}
+	return log.New(out, prefix, log.LstdFlags)
+}
+
 // Debug prints the arguments to the debug logger.
 func (sl *SimpleLogger) Debug(v ...interface{}) {
-	if sl.GetLevel() >= LevelDebug {
-		sl.debugLogger.Print(v...)
+	if Enabled(sl.GetLevel(), LevelDebug) {
+		sl.output(sl.debugLogger, LevelDebug, fmt.Sprint(v...))
 	}
 }
 
 // Debugf prints the arguments to the debug logger. Arguments are handled like in fmt.Printf.
 func (sl *SimpleLogger) Debugf(format string, v ...interface{}) {
-	if sl.GetLevel() >= LevelDebug {
-		sl.debugLogger.Printf(format, v...)
+	if Enabled(sl.GetLevel(), LevelDebug) {
+		sl.output(sl.debugLogger, LevelDebug, fmt.Sprintf(format, v...))
 	}
 }
 
 // Info prints the arguments to the info logger.
 func (sl *SimpleLogger) Info(v ...interface{}) {
-	if sl.GetLevel() >= LevelInfo {
-		sl.infoLogger.Print(v...)
+	if Enabled(sl.GetLevel(), LevelInfo) {
+		sl.output(sl.infoLogger, LevelInfo, fmt.Sprint(v...))
 	}
 }
 
 // Infof prints the arguments to the info logger. Arguments are handled like in fmt.Printf.
 func (sl *SimpleLogger) Infof(format string, v ...interface{}) {
-	if sl.GetLevel() >= LevelInfo {
-		sl.infoLogger.Printf(format, v...)
+	if Enabled(sl.GetLevel(), LevelInfo) {
+		sl.output(sl.infoLogger, LevelInfo, fmt.Sprintf(format, v...))
+	}
+}
+
+// Warn prints the arguments to the warning logger.
+func (sl *SimpleLogger) Warn(v ...interface{}) {
+	if Enabled(sl.GetLevel(), LevelWarn) {
+		sl.output(sl.warnLogger, LevelWarn, fmt.Sprint(v...))
+	}
+}
+
+// Warnf prints the arguments to the warning logger. Arguments are handled like in fmt.Printf.
+func (sl *SimpleLogger) Warnf(format string, v ...interface{}) {
+	if Enabled(sl.GetLevel(), LevelWarn) {
+		sl.output(sl.warnLogger, LevelWarn, fmt.Sprintf(format, v...))
 	}
 }
 
 // Error prints the arguments to the error logger.
 func (sl *SimpleLogger) Error(v ...interface{}) {
-	sl.errorLogger.Print(v...)
+	sl.output(sl.errorLogger, LevelError, fmt.Sprint(v...))
 }
 
 // Errorf prints the arguments to the error logger. Arguments are handled like in fmt.Printf.
 func (sl *SimpleLogger) Errorf(format string, v ...interface{}) {
-	sl.errorLogger.Printf(format, v...)
+	sl.output(sl.errorLogger, LevelError, fmt.Sprintf(format, v...))
 }
 
 // Fatal prints the arguments to the error logger, followed by a call to os.Exit(1).
 func (sl *SimpleLogger) Fatal(v ...interface{}) {
-	sl.errorLogger.Fatal(v...)
+	sl.output(sl.errorLogger, LevelError, fmt.Sprint(v...))
+	os.Exit(1)
 }
 
 // Fatalf prints the arguments to the error logger, followed by a call to os.Exit(1).
 // Arguments are handled like in fmt.Printf.
 func (sl *SimpleLogger) Fatalf(format string, v ...interface{}) {
-	sl.errorLogger.Fatalf(format, v...)
+	sl.output(sl.errorLogger, LevelError, fmt.Sprintf(format, v...))
+	os.Exit(1)
 }
 
 // Print prints the arguemtns to the info logger. It's good for standar logger compatibility
 func (sl *SimpleLogger) Print(v ...interface{}) {
-	sl.infoLogger.Print(v...)
+	sl.output(sl.infoLogger, LevelInfo, fmt.Sprint(v...))
 }
 
-// SetLevel sets the log level (0=ERROR, 1=INFO, 2=DEBUG)
+// SetLevel sets the log level: LevelError, LevelWarn, LevelInfo or LevelDebug,
+// from the least to the most verbose (see Enabled). For named loggers, only the
+// level of that name is changed.
 func (sl *SimpleLogger) SetLevel(level int) {
-	sl.level = level
+	sl.levels.Set(sl.name, level, 0)
 }
 
 // GetLevel returns the current log level
 func (sl *SimpleLogger) GetLevel() int {
-	return sl.level
+	return sl.level.Get()
 }
 
-// LogToSlack sends a message to the configured channel, if it's enabled
-func (sl *SimpleLogger) LogToSlack(webHook, title, text string, logSettings LogSettings) {
-	go func() {
+// Named returns a child logger with its own level, which follows the root level
+// until it's changed. Entries include the name in the 'logger' field.
+func (sl *SimpleLogger) Named(name string) Logger {
+	child := sl.With("logger", name).(*SimpleLogger)
+	child.name = name
+	child.level = sl.levels.Level(name)
+	return child
+}
 
-		if logSettings.GetSlackEnabled() {
-			if err := SendAlert(webHook, "", title, ColorGood, text, logSettings); err != nil {
-				sl.Errorf("Found an error sending notification to Slack: %s", err)
-			}
+// Levels returns the registry with the levels of this logger and its named children
+func (sl *SimpleLogger) Levels() *LevelRegistry {
+	return sl.levels
+}
+
+// WithFields returns a logger that includes the indicated fields in every entry,
+// along with the fields already defined in sl. The new logger shares the output
+// and the log level with sl.
+func (sl *SimpleLogger) WithFields(fields map[string]interface{}) Logger {
+	child := *sl
+	child.fields = make(map[string]interface{}, len(sl.fields)+len(fields))
+
+	for k, v := range sl.fields {
+		child.fields[k] = v
+	}
+	for k, v := range fields {
+		child.fields[k] = v
+	}
+
+	return &child
+}
+
+// With returns a logger that includes the field key=value in every entry
+func (sl *SimpleLogger) With(key string, value interface{}) Logger {
+	return sl.WithFields(map[string]interface{}{key: value})
+}
+
+// writes msg and the logger's fields using the configured format
+func (sl *SimpleLogger) output(l *log.Logger, level int, msg string) {
+	if sl.format == FormatJSON {
+		l.Print(sl.formatJSON(level, msg))
+	} else {
+		l.Print(sl.formatText(msg))
+	}
+}
+
+// formats the entry as: message key1=value1 key2=value2
+func (sl *SimpleLogger) formatText(msg string) string {
+	if len(sl.fields) == 0 {
+		return msg
+	}
+
+	var sb strings.Builder
+	sb.WriteString(msg)
+
+	for _, k := range sl.sortedFieldNames() {
+		sb.WriteString(fmt.Sprintf(" %s=%v", k, sl.fields[k]))
+	}
+
+	return sb.String()
+}
+
+// formats the entry as a JSON object, with the fields at the top level
+func (sl *SimpleLogger) formatJSON(level int, msg string) string {
+	entry := make(map[string]interface{}, len(sl.fields)+3)
+
+	for k, v := range sl.fields {
+		// errors are encoded as {} by encoding/json
+		if err, ok := v.(error); ok {
+			v = err.Error()
 		}
-	}()
+		entry[k] = v
+	}
+
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["level"] = levelNames[level]
+	entry["msg"] = msg
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		// some field can't be encoded, so use its string representation
+		for k, v := range sl.fields {
+			entry[k] = fmt.Sprint(v)
+		}
+		b, _ = json.Marshal(entry)
+	}
+
+	return string(b)
+}
+
+func (sl *SimpleLogger) sortedFieldNames() []string {
+	names := make([]string, 0, len(sl.fields))
+	for k := range sl.fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LogToSlack sends a message to the configured channel, if it's enabled. Messages
+// are queued and sent in background (see AlertDispatcher).
+func (sl *SimpleLogger) LogToSlack(webHook, title, text string, logSettings LogSettings) {
+	if logSettings.GetSlackEnabled() {
+		sl.dispatchAlert(webHook, Alert{Title: title, Text: text, Severity: SeverityInfo}, logSettings)
+	}
 }
 
 // LogErrorToSlack sends a message formatted as error to the configured channel, if it's enabled
 func (sl *SimpleLogger) LogErrorToSlack(webHook, title, text string, logSettings LogSettings) {
-	go func() {
-		if logSettings.GetSlackEnabled() {
-			if err := SendAlert(webHook, "", title, ColorDanger, fmt.Sprintf("`%s`", text), logSettings); err != nil {
+	if logSettings.GetSlackEnabled() {
+		sl.dispatchAlert(webHook, Alert{Title: title, Text: text, Severity: SeverityError}, logSettings)
+	}
+}
+
+// Close sends the queued alerts and stops the background dispatcher. If ctx is
+// done before the alerts are sent, they're discarded.
+func (sl *SimpleLogger) Close(ctx context.Context) error {
+	// prevent the creation of the dispatcher after closing
+	sl.alerts.once.Do(func() {})
+
+	if sl.alerts.dispatcher != nil {
+		return sl.alerts.dispatcher.Close(ctx)
+	}
+	return nil
+}
+
+func (sl *SimpleLogger) dispatchAlert(webHook string, alert Alert, logSettings LogSettings) {
+	sl.alerts.once.Do(func() {
+		config := sl.alerts.config
+		if config.OnError == nil {
+			config.OnError = func(alert Alert, err error) {
 				sl.Errorf("Found an error sending notification to Slack: %s", err)
 			}
 		}
-	}()
+		sl.alerts.dispatcher = NewAlertDispatcher(config)
+	})
+
+	if sl.alerts.dispatcher == nil || !sl.alerts.dispatcher.Dispatch(NewSlackAlerter(webHook, logSettings), alert) {
+		sl.Errorf("Notification to Slack discarded: %s", alert.Title)
+	}
 }
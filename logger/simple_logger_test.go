@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimpleLoggerLevels(t *testing.T) {
+	buf := new(bytes.Buffer)
+	sl := NewSimpleLoggerWithConfig(Config{Level: LevelWarn, Output: buf})
+
+	sl.Debug("debug message")
+	sl.Info("info message")
+	sl.Warn("warn message")
+	sl.Errorf("error %s", "message")
+
+	out := buf.String()
+	assert.NotContains(t, out, "debug message")
+	assert.NotContains(t, out, "info message")
+	assert.Contains(t, out, "[WRN] ")
+	assert.Contains(t, out, "warn message")
+	assert.Contains(t, out, "[ERR] ")
+	assert.Contains(t, out, "error message")
+}
+
+func TestSimpleLoggerTextFields(t *testing.T) {
+	buf := new(bytes.Buffer)
+	sl := NewSimpleLoggerWithConfig(Config{Level: LevelInfo, Output: buf})
+
+	sl.WithFields(map[string]interface{}{"user": 12, "action": "login"}).Info("done")
+
+	if !strings.HasSuffix(buf.String(), "done action=login user=12\n") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestSimpleLoggerJSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	sl := NewSimpleLoggerWithConfig(Config{Level: LevelInfo, Format: FormatJSON, Output: buf})
+
+	child := sl.With("service", "auth-service").With("request_id", "abc")
+	child.Warnf("slow response: %dms", 1500)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("output is not valid JSON: %s", buf.String())
+	}
+
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "slow response: 1500ms", entry["msg"])
+	assert.Equal(t, "auth-service", entry["service"])
+	assert.Equal(t, "abc", entry["request_id"])
+	assert.NotEmpty(t, entry["time"])
+
+	// parent's fields are not modified by the children
+	buf.Reset()
+	sl.Info("parent")
+	assert.NotContains(t, buf.String(), "service")
+
+	// level is shared
+	child.SetLevel(LevelError)
+	assert.Equal(t, LevelError, sl.GetLevel())
+}
@@ -1,11 +1,13 @@
 package logger
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
-
-	"github.com/parnurzeal/gorequest"
 )
 
 // Slack colors for messages
@@ -15,58 +17,189 @@ const (
 	ColorWarning = "warning"
 )
 
+// ErrInvalidChannel is returned when the alert destination is empty
+var ErrInvalidChannel = errors.New("Invalid channel")
+
+// Slack block and text object types
+const (
+	SlackBlockSection = "section"
+	SlackBlockContext = "context"
+	SlackBlockDivider = "divider"
+	SlackBlockHeader  = "header"
+	SlackTextMarkdown = "mrkdwn"
+	SlackTextPlain    = "plain_text"
+)
+
+// SlackMessage is the payload sent to a Slack webhook. It should be built with
+// the types in this file, so it's always encoded as valid JSON.
+type SlackMessage struct {
+	Username    string            `json:"username,omitempty"`
+	IconEmoji   string            `json:"icon_emoji,omitempty"`
+	Channel     string            `json:"channel,omitempty"`
+	Text        string            `json:"text,omitempty"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+	Blocks      []SlackBlock      `json:"blocks,omitempty"`
+}
+
+// SlackAttachment is a legacy message attachment; it's still the only way to
+// show the colored bar next to the message.
+type SlackAttachment struct {
+	Fallback  string       `json:"fallback,omitempty"`
+	Color     string       `json:"color,omitempty"`
+	Pretext   string       `json:"pretext,omitempty"`
+	Title     string       `json:"title,omitempty"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Text      string       `json:"text,omitempty"`
+	Fields    []SlackField `json:"fields,omitempty"`
+	Footer    string       `json:"footer,omitempty"`
+	Timestamp int64        `json:"ts,omitempty"`
+	MrkdwnIn  []string     `json:"mrkdwn_in,omitempty"`
+	Blocks    []SlackBlock `json:"blocks,omitempty"`
+}
+
+// SlackField is a title/value pair shown in an attachment
+type SlackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short,omitempty"`
+}
+
+// SlackTextObject is a Block Kit text element (mrkdwn or plain_text)
+type SlackTextObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackBlock is a Block Kit layout block. Only the fields used by the block
+// type must be set; use the Slack*Block functions to create them.
+type SlackBlock struct {
+	Type     string            `json:"type"`
+	BlockID  string            `json:"block_id,omitempty"`
+	Text     *SlackTextObject  `json:"text,omitempty"`
+	Fields   []SlackTextObject `json:"fields,omitempty"`
+	Elements []SlackTextObject `json:"elements,omitempty"`
+}
+
+// SlackMarkdown creates a mrkdwn text object
+func SlackMarkdown(text string) SlackTextObject {
+	return SlackTextObject{Type: SlackTextMarkdown, Text: text}
+}
+
+// SlackPlainText creates a plain_text text object
+func SlackPlainText(text string) SlackTextObject {
+	return SlackTextObject{Type: SlackTextPlain, Text: text}
+}
+
+// SlackSectionBlock creates a section with a mrkdwn text and, optionally, fields
+// shown in two columns
+func SlackSectionBlock(text string, fields ...SlackTextObject) SlackBlock {
+	block := SlackBlock{Type: SlackBlockSection, Fields: fields}
+	if text != "" {
+		t := SlackMarkdown(text)
+		block.Text = &t
+	}
+	return block
+}
+
+// SlackHeaderBlock creates a header with the indicated plain text
+func SlackHeaderBlock(text string) SlackBlock {
+	t := SlackPlainText(text)
+	return SlackBlock{Type: SlackBlockHeader, Text: &t}
+}
+
+// SlackContextBlock creates a context block, shown in small font
+func SlackContextBlock(elements ...SlackTextObject) SlackBlock {
+	return SlackBlock{Type: SlackBlockContext, Elements: elements}
+}
+
+// SlackDividerBlock creates a divider
+func SlackDividerBlock() SlackBlock {
+	return SlackBlock{Type: SlackBlockDivider}
+}
+
+// SlackCodeBlock returns text formatted as a mrkdwn code block
+func SlackCodeBlock(text string) string {
+	// a triple backtick inside the text would close the block, so a zero-width
+	// space is added to break it
+	return "```" + strings.Replace(text, "```", "`\u200b``", -1) + "```"
+}
+
+// SlackEscape escapes the characters with special meaning in mrkdwn (&, < and >),
+// so user provided text isn't interpreted as links or mentions
+func SlackEscape(text string) string {
+	text = strings.Replace(text, "&", "&amp;", -1)
+	text = strings.Replace(text, "<", "&lt;", -1)
+	return strings.Replace(text, ">", "&gt;", -1)
+}
+
+// SendSlackMessage sends the message to the indicated webhook; settings provide
+// the HTTP client and, if msg doesn't have one, the username.
+func SendSlackMessage(webHook string, msg *SlackMessage, settings LogSettings) error {
+	return SendSlackMessageContext(context.Background(), webHook, msg, settings)
+}
+
+// SendSlackMessageContext is like SendSlackMessage, but the request is cancelled
+// when ctx is done. Error responses are returned as *HTTPError.
+func SendSlackMessageContext(ctx context.Context, webHook string, msg *SlackMessage, settings LogSettings) (err error) {
+	if webHook == "" {
+		return ErrInvalidChannel
+	}
+
+	if msg.Username == "" {
+		// the caller's message is left untouched
+		copied := *msg
+		copied.Username = settings.GetAppName()
+		msg = &copied
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("Error encoding Slack message: %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webHook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Error sending message to Slack: %s", withoutURL(err))
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := settings.GetHTTPClient()
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error sending message to Slack: %w", withoutURL(err))
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		err = newHTTPError("Slack", response)
+	}
+
+	return
+}
+
 // SendAlert sends a notification to the specified slack channel
 func SendAlert(channel, username, title, color, text string, settings LogSettings) (err error) {
+	if color == "" {
+		color = ColorGood
+	}
 
-	if channel != "" {
-
-		// control parameters are valid
-		if username == "" {
-			username = settings.GetAppName()
-		}
-		if color == "" {
-			color = ColorGood
-		}
-
-		template := `
-		{
-			"username": "$USERNAME",
-			"attachments": [
-				{
-					"title": "$TITLE",
-					"color": "$COLOR",
-					"text": "$TEXT"
-				}
-			]
-		}
-		`
-
-		// replace custom data
-		msg := strings.Replace(template, "$USERNAME", username, 1)
-		msg = strings.Replace(msg, "$TITLE", title, 1)
-		msg = strings.Replace(msg, "$COLOR", color, 1)
-		msg = strings.Replace(msg, "$TEXT", text, 1)
-
-		// send message using HTTP
-		agent := gorequest.New()
-		agent.Client = settings.GetHTTPClient()
-
-		response, _, errPost := agent.Post(channel).Send(msg).End()
-
-		if response != nil {
-			defer response.Body.Close()
-		}
-
-		if errPost == nil {
-			if response.StatusCode != http.StatusOK {
-				err = fmt.Errorf("Slack returned status %s", response.Status)
-			}
-		} else {
-			err = fmt.Errorf("Error sending message to Slack: %s", errPost[0])
-		}
-	} else {
-		err = fmt.Errorf("Invalid channel")
+	msg := &SlackMessage{
+		Username: username,
+		Attachments: []SlackAttachment{
+			{
+				Fallback: title,
+				Title:    title,
+				Color:    color,
+				Text:     text,
+				MrkdwnIn: []string{"text"},
+			},
+		},
 	}
 
-	return
+	return SendSlackMessage(channel, msg, settings)
 }
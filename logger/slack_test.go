@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testSettings struct {
+	enabled bool
+	client  *http.Client
+}
+
+func (ts testSettings) GetSlackEnabled() bool       { return ts.enabled }
+func (ts testSettings) GetHTTPClient() *http.Client { return ts.client }
+func (ts testSettings) GetAppName() string          { return "test-app" }
+
+func TestSendAlertEscaping(t *testing.T) {
+	var received SlackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	title := `Error in "auth-service"`
+	text := "line 1\nline 2 \\ path C:\\tmp\t`quoted`"
+
+	err := SendAlert(server.URL, "", title, ColorDanger, text, testSettings{enabled: true})
+	if assert.NoError(t, err) && assert.Len(t, received.Attachments, 1) {
+		assert.Equal(t, "test-app", received.Username)
+		assert.Equal(t, title, received.Attachments[0].Title)
+		assert.Equal(t, text, received.Attachments[0].Text)
+		assert.Equal(t, ColorDanger, received.Attachments[0].Color)
+	}
+}
+
+func TestSlackBlocks(t *testing.T) {
+	msg := SlackMessage{
+		Blocks: []SlackBlock{
+			SlackHeaderBlock("Deploy finished"),
+			SlackSectionBlock("*Status:* ok", SlackMarkdown("*Env*\nprod"), SlackMarkdown("*Version*\n1.2.3")),
+			SlackDividerBlock(),
+			SlackContextBlock(SlackPlainText("by ci")),
+		},
+	}
+
+	b, err := json.Marshal(msg)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"blocks":[
+			{"type":"header","text":{"type":"plain_text","text":"Deploy finished"}},
+			{"type":"section","text":{"type":"mrkdwn","text":"*Status:* ok"},"fields":[{"type":"mrkdwn","text":"*Env*\nprod"},{"type":"mrkdwn","text":"*Version*\n1.2.3"}]},
+			{"type":"divider"},
+			{"type":"context","elements":[{"type":"plain_text","text":"by ci"}]}
+		]}`, string(b))
+	}
+
+	assert.Equal(t, "```a `\u200b`` b```", SlackCodeBlock("a ``` b"))
+	assert.Equal(t, "&lt;@here&gt; &amp; co", SlackEscape("<@here> & co"))
+}
+
+func TestSendSlackMessageKeepsMessage(t *testing.T) {
+	var received SlackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	msg := &SlackMessage{Text: "hello"}
+	err := SendSlackMessage(server.URL, msg, testSettings{enabled: true})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "test-app", received.Username)
+		assert.Empty(t, msg.Username)
+	}
+}
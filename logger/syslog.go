@@ -0,0 +1,55 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package logger
+
+import (
+	"log/syslog"
+)
+
+// SyslogWriter sends the log entries to syslog, using the syslog priority that
+// matches the level of each entry.
+type SyslogWriter struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogWriter connects to the syslog daemon; if network and raddr are empty,
+// it connects to the local one. Entries are sent using the user facility.
+func NewSyslogWriter(network, raddr, tag string) (*SyslogWriter, error) {
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogWriter{writer: w}, nil
+}
+
+// Write sends p with the info priority
+func (sw *SyslogWriter) Write(p []byte) (n int, err error) {
+	return sw.writer.Write(p)
+}
+
+// WriteLevel sends p with the priority associated to level
+func (sw *SyslogWriter) WriteLevel(level int, p []byte) (n int, err error) {
+	msg := string(p)
+
+	switch {
+	case level <= LevelError:
+		err = sw.writer.Err(msg)
+	case level == LevelWarn:
+		err = sw.writer.Warning(msg)
+	case level == LevelInfo:
+		err = sw.writer.Info(msg)
+	default:
+		err = sw.writer.Debug(msg)
+	}
+
+	if err == nil {
+		n = len(p)
+	}
+	return
+}
+
+// Close closes the connection to the syslog daemon
+func (sw *SyslogWriter) Close() error {
+	return sw.writer.Close()
+}
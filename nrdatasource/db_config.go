@@ -1,17 +1,10 @@
 package nrdatasource
 
 import (
-	"time"
+	"github.com/tuckyapps/lit-go-tools/datasource"
 )
 
 // DBConfig holds the configuration required to initialize a data source
-type DBConfig struct {
-	DBName             string
-	Driver             string
-	Address            string
-	Username           string
-	Password           string
-	MaxOpenConnections int
-	MaxIdleConnections int
-	ConnectionLifetime time.Duration
-}
+//
+// Deprecated: use datasource.DBConfig.
+type DBConfig = datasource.DBConfig
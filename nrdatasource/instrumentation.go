@@ -0,0 +1,30 @@
+package nrdatasource
+
+import (
+	"github.com/tuckyapps/lit-go-tools/datasource"
+
+	// register the New Relic drivers: nrmysql, nrpostgres and nrsqlite3
+	_ "github.com/newrelic/go-agent/_integrations/nrmysql"
+	_ "github.com/newrelic/go-agent/_integrations/nrpq"
+	_ "github.com/newrelic/go-agent/_integrations/nrsqlite3"
+)
+
+func init() {
+	datasource.RegisterInstrumentation(datasource.InstrumentationNewRelic, Instrumentation)
+}
+
+// drivers registered by the New Relic integrations
+var newRelicDrivers = map[string]string{
+	"mysql":    "nrmysql",
+	"postgres": "nrpostgres",
+	"sqlite3":  "nrsqlite3",
+}
+
+// Instrumentation returns the New Relic driver that instruments driverName. It's
+// registered as datasource.InstrumentationNewRelic when the package is imported.
+func Instrumentation(driverName string) (string, error) {
+	if name, found := newRelicDrivers[driverName]; found {
+		return name, nil
+	}
+	return "", datasource.ErrDriverNotSupported
+}
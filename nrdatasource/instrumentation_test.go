@@ -0,0 +1,42 @@
+package nrdatasource
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/datasource"
+)
+
+// returns true if the connections of db use the New Relic driver
+func instrumented(db *sqlx.DB) bool {
+	_, plain := db.Driver().(*sqlite3.SQLiteDriver)
+	return !plain
+}
+
+func TestInstrumentation(t *testing.T) {
+	g := new(datasource.Generic)
+	db, err := g.New(datasource.DBConfig{Driver: "sqlite3", DBName: ":memory:", Instrumentation: datasource.InstrumentationNewRelic})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer g.Close()
+
+	assert.True(t, instrumented(db))
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	assert.NoError(t, err)
+
+	_, err = Instrumentation("oracle")
+	assert.Equal(t, datasource.ErrDriverNotSupported, err)
+}
+
+func TestGenericDefaultsToNewRelic(t *testing.T) {
+	g := new(Generic)
+	_, err := g.New(DBConfig{Driver: "sqlite3", DBName: ":memory:"})
+	if assert.NoError(t, err) {
+		db, _ := g.Get()
+		assert.True(t, instrumented(db))
+		g.Close()
+	}
+}
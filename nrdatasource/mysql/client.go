@@ -1,19 +1,26 @@
+// Package mysql connects to MySQL with the New Relic driver.
+//
+// Deprecated: use datasource/mysql, with the DriverName option set to nrmysql.
 package mysql
 
 import (
-	"fmt"
-
-	// import MySQL driver
 	"github.com/jmoiron/sqlx"
+	"github.com/tuckyapps/lit-go-tools/datasource/mysql"
+	// import MySQL driver
 	_ "github.com/newrelic/go-agent/_integrations/nrmysql"
 )
 
 // Init connects to the database server
 func Init(address, username, password, dbName string) (db *sqlx.DB, err error) {
-	db, err = sqlx.Open("nrmysql", fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=True", username, password, address, dbName))
-	return
+	return mysql.Open(mysql.Options{
+		Address:    address,
+		Username:   username,
+		Password:   password,
+		DBName:     dbName,
+		DriverName: "nrmysql",
+	})
 }
 
 func RandFuncName() string {
-	return "rand()"
+	return mysql.RandFuncName()
 }
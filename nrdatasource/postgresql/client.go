@@ -1,24 +1,27 @@
+// Package postgresql connects to PostgreSQL with the New Relic driver.
+//
+// Deprecated: use datasource/postgresql, with the DriverName option set to
+// nrpostgres.
 package postgresql
 
 import (
-	"fmt"
-
 	"github.com/jmoiron/sqlx"
+	"github.com/tuckyapps/lit-go-tools/datasource/postgresql"
 	// import PostgreSQL driver
 	_ "github.com/newrelic/go-agent/_integrations/nrpq"
 )
 
 // Init connects to the database server
 func Init(address, username, password string, dbName string) (db *sqlx.DB, err error) {
-
-	db, err = sqlx.Open("nrpostgres", fmt.Sprintf("postgres://%s:%s@%v/%s", username, password, address, dbName))
-	if err == nil {
-		err = db.Ping()
-	}
-
-	return
+	return postgresql.Open(postgresql.Options{
+		Address:    address,
+		Username:   username,
+		Password:   password,
+		DBName:     dbName,
+		DriverName: "nrpostgres",
+	})
 }
 
 func RandFuncName() string {
-	return "rand()"
+	return postgresql.RandFuncName()
 }
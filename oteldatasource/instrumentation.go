@@ -0,0 +1,44 @@
+// Package oteldatasource instruments the databases with OpenTelemetry. It's a
+// separate module, so the OpenTelemetry SDK (and the Go version it requires)
+// is only needed when it's used.
+package oteldatasource
+
+import (
+	"sync"
+
+	"github.com/XSAM/otelsql"
+	"github.com/tuckyapps/lit-go-tools/datasource"
+)
+
+// registered as datasource.InstrumentationOpenTelemetry, using the global
+// tracer and meter providers
+func init() {
+	datasource.RegisterInstrumentation(datasource.InstrumentationOpenTelemetry, Instrumentation())
+}
+
+// Instrumentation returns an instrumentation that wraps the drivers with
+// otelsql and the options. Each driver is registered once, the first time it's
+// used; e.g. to use other providers:
+//
+//	datasource.RegisterInstrumentation(datasource.InstrumentationOpenTelemetry,
+//		oteldatasource.Instrumentation(otelsql.WithTracerProvider(provider)))
+func Instrumentation(options ...otelsql.Option) datasource.Instrumentation {
+	var mutex sync.Mutex
+	registered := make(map[string]string)
+
+	return func(driverName string) (string, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if name, found := registered[driverName]; found {
+			return name, nil
+		}
+
+		name, err := otelsql.Register(driverName, options...)
+		if err != nil {
+			return "", err
+		}
+		registered[driverName] = name
+		return name, nil
+	}
+}
@@ -0,0 +1,46 @@
+package oteldatasource
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/tuckyapps/lit-go-tools/datasource"
+)
+
+// returns true if the connections of db use the OpenTelemetry driver
+func instrumented(db *sqlx.DB) bool {
+	_, plain := db.Driver().(*sqlite3.SQLiteDriver)
+	return !plain
+}
+
+func TestInstrumentation(t *testing.T) {
+	config := datasource.DBConfig{Driver: "sqlite3", DBName: ":memory:", Instrumentation: datasource.InstrumentationOpenTelemetry}
+
+	for i := 0; i < 2; i++ {
+		g := new(datasource.Generic)
+		db, err := g.New(config)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.True(t, instrumented(db))
+		_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+		assert.NoError(t, err)
+		g.Close()
+	}
+
+	// registered once
+	drivers := 0
+	for _, name := range sql.Drivers() {
+		if name == "sqlite3-otelsql-0" || name == "sqlite3-otelsql-1" {
+			drivers++
+		}
+	}
+	assert.Equal(t, 1, drivers)
+
+	_, err := Instrumentation()("oracle")
+	assert.Error(t, err)
+}